@@ -0,0 +1,99 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+type backgroundProcess struct {
+	stepIndex int
+	cancel    context.CancelFunc
+	stdout    *boundedBuffer
+	stderr    *boundedBuffer
+	done      chan struct{}
+	waitErr   error
+	stopped   bool
+	result    api.BackgroundProcessResult
+}
+
+func startBackgroundProcess(
+	stepIndex int,
+	process api.CLIStepBackgroundProcess,
+	variables map[string]string,
+) (*backgroundProcess, api.BackgroundProcessResult) {
+	finalCommand := InterpolateVariables(process.Command, variables)
+	result := api.BackgroundProcessResult{
+		FinalCommand: finalCommand,
+		Command:      process,
+		Variables:    maps.Clone(variables),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := newShellCommand(ctx, finalCommand)
+	stdout := newBoundedBuffer(maxCLIOutputBytesPerStream)
+	stderr := newBoundedBuffer(maxCLIOutputBytesPerStream)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		result.Err = fmt.Sprintf("failed to start background process: %s", err)
+		result.ExitCode = api.ExitCodeInternalFailure
+		return nil, result
+	}
+
+	p := &backgroundProcess{
+		stepIndex: stepIndex,
+		cancel:    cancel,
+		stdout:    stdout,
+		stderr:    stderr,
+		done:      make(chan struct{}),
+		result:    result,
+	}
+	go func() {
+		p.waitErr = cmd.Wait()
+		close(p.done)
+	}()
+
+	return p, result
+}
+
+// stop kills the process if it's still running and returns its result with the
+// captured logs. It's safe to call more than once.
+func (p *backgroundProcess) stop() api.BackgroundProcessResult {
+	if p.stopped {
+		return p.result
+	}
+	p.stopped = true
+
+	select {
+	case <-p.done:
+		p.result.ExitedEarly = true
+		p.result.ExitCode = backgroundProcessExitCode(p.waitErr)
+	default:
+		p.cancel()
+		<-p.done
+	}
+	p.cancel()
+
+	p.result.Stdout = strings.TrimRight(p.stdout.String(), " \n\t\r")
+	p.result.Stderr = strings.TrimRight(p.stderr.String(), " \n\t\r")
+	return p.result
+}
+
+func backgroundProcessExitCode(err error) int {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+	if err != nil {
+		return api.ExitCodeInternalFailure
+	}
+	return 0
+}
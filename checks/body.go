@@ -0,0 +1,189 @@
+package checks
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/goccy/go-json"
+)
+
+const (
+	maxRequestBodyFileBytes = 32 * 1024 * 1024
+
+	defaultRawContentType  = "text/plain; charset=utf-8"
+	defaultFileContentType = "application/octet-stream"
+)
+
+// requestBody is an interpolated request body along with a short description
+// of it for local output
+type requestBody struct {
+	data        []byte
+	contentType string
+	summary     string
+}
+
+func newRequestBody(request api.HTTPRequest, variables map[string]string) (*requestBody, error) {
+	bodies := 0
+	for _, set := range []bool{
+		request.BodyJSON != nil,
+		request.BodyForm != nil,
+		request.BodyRaw != nil,
+		request.BodyFile != "",
+		request.BodyMultipart != nil,
+	} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return nil, errors.New("Failed to create request body: only one body can be set")
+	}
+
+	switch {
+	case request.BodyJSON != nil:
+		bodyJSON, err := interpolateJSONStrings(request.BodyJSON, variables)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to interpolate request body: %s", err)
+		}
+		dat, err := json.Marshal(bodyJSON)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to marshal request body: %s", err)
+		}
+		return newSummarizedBody(dat, "application/json"), nil
+
+	case request.BodyForm != nil:
+		formValues := url.Values{}
+		for key, val := range request.BodyForm {
+			interpolatedVal := InterpolateVariables(val, variables)
+			formValues.Add(key, interpolatedVal)
+		}
+		return newSummarizedBody([]byte(formValues.Encode()), "application/x-www-form-urlencoded"), nil
+
+	case request.BodyRaw != nil:
+		contentType := cmp.Or(InterpolateVariables(request.ContentType, variables), defaultRawContentType)
+		return newSummarizedBody([]byte(InterpolateVariables(*request.BodyRaw, variables)), contentType), nil
+
+	case request.BodyFile != "":
+		path := InterpolateVariables(request.BodyFile, variables)
+		dat, err := readRequestBodyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		contentType := cmp.Or(InterpolateVariables(request.ContentType, variables), fileContentType(path))
+		return newSummarizedBody(dat, contentType), nil
+
+	case request.BodyMultipart != nil:
+		return newMultipartBody(*request.BodyMultipart, variables)
+
+	default:
+		return nil, nil
+	}
+}
+
+func newSummarizedBody(dat []byte, contentType string) *requestBody {
+	return &requestBody{data: dat, contentType: contentType, summary: summarizeBody(dat, contentType)}
+}
+
+// newMultipartBody writes the fields sorted by name and then the files. The
+// summary lists each part rather than the encoded body.
+func newMultipartBody(body api.HTTPRequestMultipart, variables map[string]string) (*requestBody, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	var parts []string
+
+	fieldNames := make([]string, 0, len(body.Fields))
+	for name := range body.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	slices.Sort(fieldNames)
+	for _, name := range fieldNames {
+		value := InterpolateVariables(body.Fields[name], variables)
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, fmt.Errorf("Failed to create request body: %s", err)
+		}
+		parts = append(parts, fmt.Sprintf("field %s: %s", name, summarizeBody([]byte(value), "")))
+	}
+
+	for _, file := range body.Files {
+		path := InterpolateVariables(file.Path, variables)
+		dat, err := readRequestBodyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		filename := cmp.Or(InterpolateVariables(file.Filename, variables), filepath.Base(path))
+		contentType := cmp.Or(InterpolateVariables(file.ContentType, variables), fileContentType(path))
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+			"name":     InterpolateVariables(file.Field, variables),
+			"filename": filename,
+		}))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create request body: %s", err)
+		}
+		if _, err := part.Write(dat); err != nil {
+			return nil, fmt.Errorf("Failed to create request body: %s", err)
+		}
+		parts = append(parts, fmt.Sprintf("file %s: %s (%s, %s)", file.Field, filename, contentType, FormatBytes(int64(len(dat)))))
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("Failed to create request body: %s", err)
+	}
+	return &requestBody{
+		data:        buf.Bytes(),
+		contentType: writer.FormDataContentType(),
+		summary:     "multipart/form-data\n" + strings.Join(parts, "\n"),
+	}, nil
+}
+
+func readRequestBodyFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read request body file: %s", err)
+	}
+	defer file.Close()
+
+	dat, err := io.ReadAll(io.LimitReader(file, maxRequestBodyFileBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read request body file: %s", err)
+	}
+	if len(dat) > maxRequestBodyFileBytes {
+		return nil, fmt.Errorf("Failed to read request body file: %s is larger than %s", path, FormatBytes(maxRequestBodyFileBytes))
+	}
+	return dat, nil
+}
+
+func fileContentType(path string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+	return defaultFileContentType
+}
+
+// summarizeBody keeps text bodies readable but only describes binary ones, so
+// output never dumps raw bytes into the terminal.
+func summarizeBody(dat []byte, contentType string) string {
+	if !likelyBinary(dat) {
+		return truncateAndStringifyBody(dat)
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(dat)
+	}
+	return fmt.Sprintf("[binary %s, %s]", contentType, FormatBytes(int64(len(dat))))
+}
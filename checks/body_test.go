@@ -0,0 +1,107 @@
+package checks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+func TestRunHTTPRequestSendsRawAndFileBodies(t *testing.T) {
+	var gotContentType, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotContentType, gotBody = r.Header.Get("Content-Type"), string(body)
+	}))
+	defer server.Close()
+
+	raw := "hello ${name}"
+	result := runHTTPRequest(&http.Client{}, server.URL, map[string]string{"name": "world", "type": "text/markdown"}, api.CLIStepHTTPRequest{
+		Request: api.HTTPRequest{Method: http.MethodPost, FullURL: api.BaseURLPlaceholder, BodyRaw: &raw, ContentType: "${type}"},
+	})
+	if result.Err != "" || gotBody != "hello world" || gotContentType != "text/markdown" {
+		t.Fatalf("raw body = %q (%s), err = %q", gotBody, gotContentType, result.Err)
+	}
+	if result.RequestBody != "hello world" {
+		t.Fatalf("RequestBody = %q, want the interpolated body", result.RequestBody)
+	}
+
+	image := []byte{0x89, 'P', 'N', 'G', 0, 1, 2, 0xff}
+	path := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(path, image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result = runHTTPRequest(&http.Client{}, server.URL, map[string]string{"dir": filepath.Dir(path)}, api.CLIStepHTTPRequest{
+		Request: api.HTTPRequest{Method: http.MethodPut, FullURL: api.BaseURLPlaceholder, BodyFile: "${dir}/photo.png"},
+	})
+	if result.Err != "" || gotBody != string(image) || gotContentType != "image/png" {
+		t.Fatalf("file body = %q (%s), err = %q", gotBody, gotContentType, result.Err)
+	}
+	if result.RequestBody != "[binary image/png, 8 B]" {
+		t.Fatalf("RequestBody = %q, want a binary summary", result.RequestBody)
+	}
+}
+
+func TestRunHTTPRequestSendsMultipartBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("file contents"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string][]string
+	var filename, fileContents, fileType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fields = r.MultipartForm.Value
+		file, header, err := r.FormFile("upload")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		contents, _ := io.ReadAll(file)
+		filename, fileContents, fileType = header.Filename, string(contents), header.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	result := runHTTPRequest(&http.Client{}, server.URL, map[string]string{"path": path, "title": "My notes"}, api.CLIStepHTTPRequest{
+		Request: api.HTTPRequest{
+			Method:  http.MethodPost,
+			FullURL: api.BaseURLPlaceholder,
+			BodyMultipart: &api.HTTPRequestMultipart{
+				Fields: map[string]string{"title": "${title}"},
+				Files:  []api.HTTPRequestMultipartFile{{Field: "upload", Path: "${path}", Filename: "renamed.txt"}},
+			},
+		},
+	})
+	if result.Err != "" || result.StatusCode != http.StatusOK {
+		t.Fatalf("result = %#v, want the multipart request to succeed", result)
+	}
+	if fields["title"][0] != "My notes" || filename != "renamed.txt" || fileContents != "file contents" || !strings.HasPrefix(fileType, "text/plain") {
+		t.Fatalf("fields = %v, file = %q %q %q", fields, filename, fileContents, fileType)
+	}
+	if !strings.Contains(result.RequestBody, "field title: My notes") || !strings.Contains(result.RequestBody, "file upload: renamed.txt (text/plain") {
+		t.Fatalf("RequestBody = %q, want a part summary", result.RequestBody)
+	}
+}
+
+func TestNewRequestBodyErrors(t *testing.T) {
+	raw := "x"
+	_, err := newRequestBody(api.HTTPRequest{BodyRaw: &raw, BodyForm: map[string]string{"a": "b"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "only one body can be set") {
+		t.Fatalf("err = %v, want a multiple bodies error", err)
+	}
+
+	_, err = newRequestBody(api.HTTPRequest{BodyFile: filepath.Join(t.TempDir(), "missing.bin")}, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "Failed to read request body file") {
+		t.Fatalf("err = %v, want a missing file error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package checks
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/goccy/go-json"
+)
+
+const (
+	randomStringAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	maxRandomStringLen   = 1024
+)
+
+var randomStringPattern = regexp.MustCompile(`\$\{\s*random\.string:(\d+)`)
+
+// builtinVariables generates the ${run.*} and ${random.*} variables once per
+// run, so every step that refers to one sees the same value. The same seed
+// always produces the same values, except for the timestamp.
+func builtinVariables(cliData api.CLIData, seed int64, now time.Time) map[string]string {
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(seed)>>32))
+
+	variables := map[string]string{
+		"run.uuid":      randomUUID(rng),
+		"run.timestamp": strconv.FormatInt(now.Unix(), 10),
+		"random.int":    strconv.Itoa(rng.IntN(1_000_000_000)),
+		"random.email":  fmt.Sprintf("user-%s@example.com", randomString(rng, 10)),
+	}
+	for _, length := range randomStringLengths(cliData) {
+		variables[fmt.Sprintf("random.string:%d", length)] = randomString(rng, length)
+	}
+	return variables
+}
+
+// randomStringLengths finds every ${random.string:N} the lesson uses, since
+// there's no way to generate them lazily and still keep them stable.
+func randomStringLengths(cliData api.CLIData) []int {
+	manifest, err := json.Marshal(cliData)
+	if err != nil {
+		return nil
+	}
+
+	var lengths []int
+	for _, match := range randomStringPattern.FindAllSubmatch(manifest, -1) {
+		length, err := strconv.Atoi(string(match[1]))
+		if err != nil || length > maxRandomStringLen || slices.Contains(lengths, length) {
+			continue
+		}
+		lengths = append(lengths, length)
+	}
+	slices.Sort(lengths)
+	return lengths
+}
+
+func randomString(rng *rand.Rand, length int) string {
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = randomStringAlphabet[rng.IntN(len(randomStringAlphabet))]
+	}
+	return string(b)
+}
+
+// randomUUID returns a version 4 UUID drawn from rng.
+func randomUUID(rng *rand.Rand) string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(rng.Uint32())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,61 @@
+package checks
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	api "github.com/bootdotdev/bootdev/client"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBuiltinVariablesAreSeeded(t *testing.T) {
+	data := api.CLIData{Steps: []api.CLIStep{{
+		CLICommand: &api.CLIStepCLICommand{Command: "echo ${random.string:12} ${ random.string:4 } ${random.string:12}"},
+	}}}
+	now := time.Unix(1_700_000_000, 0)
+
+	first := builtinVariables(data, 42, now)
+	second := builtinVariables(data, 42, now)
+	for name, value := range first {
+		if second[name] != value {
+			t.Errorf("%s = %q then %q, want the same value for the same seed", name, value, second[name])
+		}
+	}
+	if other := builtinVariables(data, 43, now); other["run.uuid"] == first["run.uuid"] {
+		t.Errorf("run.uuid = %q for different seeds", other["run.uuid"])
+	}
+
+	patterns := map[string]string{
+		"run.uuid":         `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		"run.timestamp":    `^1700000000$`,
+		"random.int":       `^\d+$`,
+		"random.email":     `^user-[a-z0-9]{10}@example\.com$`,
+		"random.string:12": `^[a-z0-9]{12}$`,
+		"random.string:4":  `^[a-z0-9]{4}$`,
+	}
+	if len(first) != len(patterns) {
+		t.Fatalf("builtinVariables() = %v, want %d variables", first, len(patterns))
+	}
+	for name, pattern := range patterns {
+		if !regexp.MustCompile(pattern).MatchString(first[name]) {
+			t.Errorf("%s = %q, want match for %s", name, first[name], pattern)
+		}
+	}
+}
+
+func TestCLIChecksInterpolatesBuiltinVariables(t *testing.T) {
+	data := api.CLIData{Steps: []api.CLIStep{
+		{CLICommand: &api.CLIStepCLICommand{Command: "echo ${random.email}"}},
+		{CLICommand: &api.CLIStepCLICommand{Command: "echo ${random.email}"}},
+	}}
+
+	results, err := CLIChecks(data, "", 7, func(msg tea.Msg) {})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+	first, second := results[0].CLICommandResult.Stdout, results[1].CLICommandResult.Stdout
+	if first != second || !regexp.MustCompile(`^user-[a-z0-9]+@example\.com$`).MatchString(first) {
+		t.Fatalf("stdout = %q and %q, want the same generated email", first, second)
+	}
+}
@@ -2,18 +2,29 @@ package checks
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"maps"
 	"os"
 	"os/exec"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	api "github.com/bootdotdev/bootdev/client"
 )
 
-const maxCLIOutputBytesPerStream = 1024 * 1024
+const (
+	maxCLIOutputBytesPerStream = 1024 * 1024
+	defaultCLICommandTimeout   = 60 * time.Second
+
+	// how long to wait for output pipes to close after the process is killed
+	cliCommandWaitDelay = time.Second
+)
 
 type boundedBuffer struct {
 	buffer    bytes.Buffer
@@ -44,6 +55,19 @@ func (b *boundedBuffer) String() string {
 	return b.buffer.String()
 }
 
+// resolveCLICommand picks the command variant for this OS, so the result
+// records the command that actually ran
+func resolveCLICommand(command api.CLIStepCLICommand) (api.CLIStepCLICommand, error) {
+	if len(command.Commands) == 0 {
+		return command, nil
+	}
+	command.Command = command.CommandFor(runtime.GOOS)
+	if command.Command == "" {
+		return command, fmt.Errorf("unable to run lesson: no command for %s and no default", runtime.GOOS)
+	}
+	return command, nil
+}
+
 func runCLICommand(command api.CLIStepCLICommand, variables map[string]string) (result api.CLICommandResult) {
 	return runCLICommandWithOutputLimit(command, variables, maxCLIOutputBytesPerStream)
 }
@@ -57,24 +81,45 @@ func runCLICommandWithOutputLimit(
 	result.FinalCommand = finalCommand
 	result.Command = command
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("powershell", "-Command", finalCommand)
-	} else {
-		cmd = exec.Command("sh", "-c", finalCommand)
+	stdin, err := openCLICommandStdin(command, variables)
+	if err != nil {
+		result.Err = err.Error()
+		result.Outcome, result.ExitCode = api.CLICommandInternalFailure, api.ExitCodeInternalFailure
+		result.Variables = maps.Clone(variables)
+		return result
+	}
+	if stdin != nil {
+		defer stdin.Close()
 	}
 
-	cmd.Env = append(os.Environ(), "LANG=en_US.UTF-8")
+	timeout := CLICommandTimeout(command)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd, err := newCLICommand(ctx, command.Shell, finalCommand)
+	if err != nil {
+		result.Err = err.Error()
+		result.Outcome, result.ExitCode = api.CLICommandInternalFailure, api.ExitCodeInternalFailure
+		result.Variables = maps.Clone(variables)
+		return result
+	}
 	stdout := newBoundedBuffer(maxOutputBytesPerStream)
 	stderr := newBoundedBuffer(maxOutputBytesPerStream)
+	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
-	err := cmd.Run()
-	if ee, ok := err.(*exec.ExitError); ok {
-		result.ExitCode = ee.ExitCode()
-	} else if err != nil {
-		result.ExitCode = -2
+	start := time.Now()
+	err = cmd.Run()
+	result.DurationMs = int(time.Since(start).Milliseconds())
+	if cmd.ProcessState != nil {
+		result.UserCPUMs = int(cmd.ProcessState.UserTime().Milliseconds())
+		result.MaxRSSBytes = maxRSSBytes(cmd.ProcessState)
+	}
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	result.Outcome, result.ExitCode, result.Signal = commandOutcome(err, timedOut)
+	if result.Outcome == api.CLICommandInternalFailure {
+		result.Err = fmt.Sprintf("failed to run command: %s", err)
 	}
 
 	result.Stdout = strings.TrimRight(stdout.String(), " \n\t\r")
@@ -82,10 +127,19 @@ func runCLICommandWithOutputLimit(
 	if command.StdoutFilterTmdl != nil {
 		result.Stdout = ExtractTmdlBlock(result.Stdout, *command.StdoutFilterTmdl)
 	}
+	if command.Normalize != nil {
+		result.Stdout = normalizeOutput(result.Stdout, *command.Normalize)
+		result.Stderr = normalizeOutput(result.Stderr, *command.Normalize)
+	}
+	if hasStderrTests(command) {
+		result.SubmittedStderr = result.Stderr
+	}
 
-	if stdout.truncated || stderr.truncated {
+	if timedOut {
+		result.Err = fmt.Sprintf("command timed out after %s", timeout)
+	} else if stdout.truncated || stderr.truncated {
 		result.Err = fmt.Sprintf("command output exceeded the %d-byte per-stream limit", maxOutputBytesPerStream)
-		result.ExitCode = -2
+		result.Outcome, result.ExitCode = api.CLICommandInternalFailure, api.ExitCodeInternalFailure
 	} else if err := parseStdoutVariables(result.Stdout, command.StdoutVariables, variables); err != nil {
 		result.Err = err.Error()
 	}
@@ -94,36 +148,329 @@ func runCLICommandWithOutputLimit(
 	return result
 }
 
+// commandOutcome classifies the error from cmd.Run or cmd.Wait.
+func commandOutcome(err error, timedOut bool) (outcome api.CLICommandOutcome, exitCode int, signal string) {
+	if timedOut {
+		return api.CLICommandTimedOut, api.ExitCodeInternalFailure, ""
+	}
+	if err == nil {
+		return api.CLICommandExited, 0, ""
+	}
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return api.CLICommandInternalFailure, api.ExitCodeInternalFailure, ""
+	}
+	if signal := exitSignal(ee); signal != "" {
+		return api.CLICommandSignaled, ee.ExitCode(), signal
+	}
+	return api.CLICommandExited, ee.ExitCode(), ""
+}
+
+// FormatBytes renders a byte count with a binary unit, e.g. "12.3 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// normalizeSignalName accepts "SIGTERM", "sigterm" or "TERM".
+func normalizeSignalName(signal string) string {
+	signal = strings.ToUpper(strings.TrimSpace(signal))
+	if !strings.HasPrefix(signal, "SIG") {
+		signal = "SIG" + signal
+	}
+	return signal
+}
+
+// newShellCommand runs the command through the platform's shell. Canceling ctx
+// kills the whole process tree, not just the shell.
+func newShellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return configureCommand(exec.CommandContext(ctx, "powershell", "-Command", command))
+	}
+	return configureCommand(exec.CommandContext(ctx, "sh", "-c", command))
+}
+
+// newCLICommand runs the command through the shell the step asked for, or
+// without one for CLIShellExec.
+func newCLICommand(ctx context.Context, shell api.CLIShell, command string) (*exec.Cmd, error) {
+	switch shell {
+	case "":
+		return newShellCommand(ctx, command), nil
+	case api.CLIShellSh, api.CLIShellBash, api.CLIShellZsh:
+		return configureCommand(exec.CommandContext(ctx, string(shell), "-c", command)), nil
+	case api.CLIShellExec:
+		args, err := splitCommandArgs(command)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return nil, errors.New("empty command")
+		}
+		return configureCommand(exec.CommandContext(ctx, args[0], args[1:]...)), nil
+	default:
+		return nil, fmt.Errorf("unsupported shell %q", shell)
+	}
+}
+
+func configureCommand(cmd *exec.Cmd) *exec.Cmd {
+	killProcessGroupOnCancel(cmd)
+	cmd.WaitDelay = cliCommandWaitDelay
+	cmd.Env = append(os.Environ(), "LANG=en_US.UTF-8")
+	return cmd
+}
+
+// splitCommandArgs splits a command into argv the way a shell would for simple
+// words: whitespace separates arguments, quotes group them, and a backslash
+// escapes the next character outside single quotes. Nothing is expanded.
+func splitCommandArgs(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape in command")
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// ValidateShell reports whether shell is one a CLI command step can run in.
+func ValidateShell(shell api.CLIShell) error {
+	switch shell {
+	case api.CLIShellSh, api.CLIShellBash, api.CLIShellZsh, api.CLIShellExec:
+		return nil
+	default:
+		return fmt.Errorf("unsupported shell %q: use sh, bash, zsh or exec", shell)
+	}
+}
+
+// ApplyShellOverride makes every command step that doesn't pick a shell run
+// through shell instead of the platform default. Background processes and
+// sessions have no shell setting, so they keep the platform default.
+func ApplyShellOverride(cliData api.CLIData, shell api.CLIShell) error {
+	if shell == "" {
+		return nil
+	}
+	if err := ValidateShell(shell); err != nil {
+		return err
+	}
+	for _, step := range cliData.Steps {
+		if step.CLICommand != nil && step.CLICommand.Shell == "" {
+			step.CLICommand.Shell = shell
+		}
+		if step.Parallel == nil {
+			continue
+		}
+		for _, parallelStep := range step.Parallel.Steps {
+			if parallelStep.CLICommand != nil && parallelStep.CLICommand.Shell == "" {
+				parallelStep.CLICommand.Shell = shell
+			}
+		}
+	}
+	return nil
+}
+
+// openCLICommandStdin returns the interpolated stdin or stdinFile contents for the
+// command, or nil to leave stdin connected to the null device.
+func openCLICommandStdin(command api.CLIStepCLICommand, variables map[string]string) (io.ReadCloser, error) {
+	switch {
+	case command.Stdin != nil && command.StdinFile != nil:
+		return nil, fmt.Errorf("invalid stdin configuration")
+	case command.Stdin != nil:
+		return io.NopCloser(strings.NewReader(InterpolateVariables(*command.Stdin, variables))), nil
+	case command.StdinFile != nil:
+		path := InterpolateVariables(*command.StdinFile, variables)
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open stdin file %q", path)
+		}
+		return file, nil
+	default:
+		return nil, nil
+	}
+}
+
+// applyCommandTimeoutDefault gives every CLI command and session that doesn't
+// set timeoutMs the lesson's commandTimeoutMs. Without one, they keep using
+// defaultCLICommandTimeout.
+func applyCommandTimeoutDefault(cliData api.CLIData) {
+	if cliData.CommandTimeoutMs == nil || *cliData.CommandTimeoutMs <= 0 {
+		return
+	}
+	for _, step := range cliData.Steps {
+		if step.CLICommand != nil && step.CLICommand.TimeoutMs == nil {
+			step.CLICommand.TimeoutMs = cliData.CommandTimeoutMs
+		}
+		if step.Session != nil && step.Session.TimeoutMs == nil {
+			step.Session.TimeoutMs = cliData.CommandTimeoutMs
+		}
+		if step.Parallel == nil {
+			continue
+		}
+		for _, parallelStep := range step.Parallel.Steps {
+			if parallelStep.CLICommand != nil && parallelStep.CLICommand.TimeoutMs == nil {
+				parallelStep.CLICommand.TimeoutMs = cliData.CommandTimeoutMs
+			}
+		}
+	}
+}
+
+// CLICommandTimeout returns the step's timeoutMs, or the default when it's unset.
+func CLICommandTimeout(command api.CLIStepCLICommand) time.Duration {
+	return durationFromMs(command.TimeoutMs, defaultCLICommandTimeout)
+}
+
+func hasStderrTests(command api.CLIStepCLICommand) bool {
+	for _, test := range command.Tests {
+		if test.StderrContainsAll != nil || test.StderrContainsNone != nil || test.StderrJq != nil || test.StderrEmpty != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func parseStdoutVariables(stdout string, vardefs []api.CLICommandStdoutVariable, variables map[string]string) error {
 	for _, vardef := range vardefs {
-		if vardef.Name == "" {
+		var (
+			found bool
+			err   error
+		)
+		switch {
+		case vardef.Path != "" && vardef.Regex != "":
 			return fmt.Errorf("invalid stdout variable configuration")
-		}
-		if vardef.Regex == "" {
+		case vardef.Path != "":
+			found, err = parseStdoutJqVariable(stdout, vardef, variables)
+		case vardef.Regex != "":
+			found, err = parseStdoutRegexVariables(stdout, vardef, variables)
+		default:
 			return fmt.Errorf("invalid stdout variable configuration")
 		}
-		re, err := regexp.Compile(vardef.Regex)
 		if err != nil {
-			return fmt.Errorf("invalid stdout variable configuration")
-		}
-		if re.NumSubexp() != 1 {
-			return fmt.Errorf("invalid stdout variable configuration")
+			return err
 		}
 
-		matches := re.FindStringSubmatch(stdout)
-		if len(matches) == 2 {
-			variables[vardef.Name] = matches[1]
+		if !found && vardef.Required {
+			return fmt.Errorf("required stdout variable %s was not found", describeStdoutVariable(vardef))
 		}
 	}
 
 	return nil
 }
 
+func parseStdoutJqVariable(stdout string, vardef api.CLICommandStdoutVariable, variables map[string]string) (bool, error) {
+	if vardef.Name == "" {
+		return false, fmt.Errorf("invalid stdout variable configuration")
+	}
+	vals, err := valsFromJqPath(vardef.Path, stdout)
+	if err != nil {
+		return false, fmt.Errorf("failed to read stdout variable %s: %s", vardef.Name, err)
+	}
+	value, ok := jqVariableValue(vals)
+	if !ok {
+		return false, nil
+	}
+	variables[vardef.Name] = value
+	return true, nil
+}
+
+func parseStdoutRegexVariables(stdout string, vardef api.CLICommandStdoutVariable, variables map[string]string) (bool, error) {
+	re, err := regexp.Compile(vardef.Regex)
+	if err != nil {
+		return false, fmt.Errorf("invalid stdout variable configuration")
+	}
+
+	names := re.SubexpNames()[1:]
+	if vardef.Name != "" {
+		if re.NumSubexp() != 1 {
+			return false, fmt.Errorf("invalid stdout variable configuration")
+		}
+		names = []string{vardef.Name}
+	} else if len(names) == 0 || slices.Contains(names, "") {
+		return false, fmt.Errorf("invalid stdout variable configuration")
+	}
+
+	matches := re.FindStringSubmatch(stdout)
+	if matches == nil {
+		return false, nil
+	}
+	for i, name := range names {
+		variables[name] = matches[i+1]
+	}
+	return true, nil
+}
+
+func describeStdoutVariable(vardef api.CLICommandStdoutVariable) string {
+	if vardef.Name != "" {
+		return vardef.Name
+	}
+	re, err := regexp.Compile(vardef.Regex)
+	if err != nil {
+		return vardef.Regex
+	}
+	return strings.Join(re.SubexpNames()[1:], ", ")
+}
+
 func prettyPrintCLICommand(test api.CLICommandTest, variables map[string]string) string {
 	if test.ExitCode != nil {
 		return fmt.Sprintf("Expect exit code %d", *test.ExitCode)
 	}
 
+	if test.ExitCodeNonZero != nil {
+		if *test.ExitCodeNonZero {
+			return "Expect a non-zero exit code"
+		}
+		return "Expect exit code 0"
+	}
+
+	if test.ExitCodeOneOf != nil {
+		return fmt.Sprintf("Expect exit code to be one of %v", test.ExitCodeOneOf)
+	}
+
+	if test.ExitSignal != nil {
+		return fmt.Sprintf("Expect the command to be killed by signal %s", normalizeSignalName(*test.ExitSignal))
+	}
+
 	if test.StdoutLinesGT != nil {
 		return fmt.Sprintf("Expect > %d lines on stdout", *test.StdoutLinesGT)
 	}
@@ -152,5 +499,52 @@ func prettyPrintCLICommand(test api.CLICommandTest, variables map[string]string)
 		return prettyPrintStdoutJqTest(*test.StdoutJq, variables)
 	}
 
+	if test.StderrContainsAll != nil {
+		var str strings.Builder
+		str.WriteString("Expect stderr to contain all of:")
+		for _, contains := range test.StderrContainsAll {
+			interpolatedContains := InterpolateVariables(contains, variables)
+			fmt.Fprintf(&str, "\n      - '%s'", interpolatedContains)
+		}
+		return str.String()
+	}
+
+	if test.StderrContainsNone != nil {
+		var str strings.Builder
+		str.WriteString("Expect stderr to contain none of:")
+		for _, containsNone := range test.StderrContainsNone {
+			interpolatedContainsNone := InterpolateVariables(containsNone, variables)
+			fmt.Fprintf(&str, "\n      - '%s'", interpolatedContainsNone)
+		}
+		return str.String()
+	}
+
+	if test.StderrJq != nil {
+		return prettyPrintStderrJqTest(*test.StderrJq, variables)
+	}
+
+	if test.StderrEmpty != nil {
+		if *test.StderrEmpty {
+			return "Expect stderr to be empty"
+		}
+		return "Expect stderr to not be empty"
+	}
+
+	if test.MaxDurationMs != nil {
+		return fmt.Sprintf("Expect command to finish within %dms", *test.MaxDurationMs)
+	}
+
+	if test.MaxRSSBytes != nil {
+		return fmt.Sprintf("Expect max RSS of at most %s", FormatBytes(*test.MaxRSSBytes))
+	}
+
+	if test.StdoutLines != nil {
+		return prettyPrintStdoutLinesTest(*test.StdoutLines, variables)
+	}
+
+	if test.StdoutSnapshot != nil {
+		return prettyPrintStdoutSnapshotTest(*test.StdoutSnapshot)
+	}
+
 	return ""
 }
@@ -1,11 +1,18 @@
 package checks
 
 import (
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	api "github.com/bootdotdev/bootdev/client"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func TestRunCLICommandCapsOutput(t *testing.T) {
@@ -142,6 +149,18 @@ func TestParseStdoutVariablesUsesGenericConfigurationError(t *testing.T) {
 			name:   "too many capture groups",
 			vardef: api.CLICommandStdoutVariable{Name: "token", Regex: `token=([a-z]+)([0-9]+)`},
 		},
+		{
+			name:   "unnamed group without a name",
+			vardef: api.CLICommandStdoutVariable{Regex: `token=(?P<word>[a-z]+)([0-9]+)`},
+		},
+		{
+			name:   "regex and path",
+			vardef: api.CLICommandStdoutVariable{Name: "token", Regex: `token=(.+)`, Path: ".token"},
+		},
+		{
+			name:   "path without a name",
+			vardef: api.CLICommandStdoutVariable{Path: ".token"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -157,3 +176,391 @@ func TestParseStdoutVariablesUsesGenericConfigurationError(t *testing.T) {
 		})
 	}
 }
+
+func TestRunCLICommandKillsProcessGroupOnTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are unix-only")
+	}
+
+	start := time.Now()
+	result := runCLICommand(api.CLIStepCLICommand{
+		Command:   `(sleep 5; echo late) & echo early; sleep 5`,
+		TimeoutMs: intPtr(200),
+	}, map[string]string{})
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("command took %s, want it killed after the timeout", elapsed)
+	}
+	if result.Outcome != api.CLICommandTimedOut {
+		t.Fatalf("outcome = %q, want timed out", result.Outcome)
+	}
+	if !strings.Contains(result.Err, "timed out after 200ms") {
+		t.Fatalf("command error = %q, want timeout error", result.Err)
+	}
+	if result.Stdout != "early" {
+		t.Fatalf("stdout = %q, want output captured before the timeout", result.Stdout)
+	}
+	if failure := evaluateCLICommandTests(0, api.CLIStepCLICommand{}, result); failure == nil {
+		t.Fatal("timed out command unexpectedly passed")
+	}
+}
+
+func TestRunCLICommandPipesInterpolatedStdin(t *testing.T) {
+	command := `cat`
+	if runtime.GOOS == "windows" {
+		command = `$input`
+	}
+
+	result := runCLICommand(api.CLIStepCLICommand{
+		Command: command,
+		Stdin:   stringPtr("help\n${cmd}\n"),
+	}, map[string]string{"cmd": "exit"})
+
+	if result.Err != "" {
+		t.Fatalf("unexpected command error: %s", result.Err)
+	}
+	if want := "help\nexit"; result.Stdout != want {
+		t.Fatalf("stdout = %q, want %q", result.Stdout, want)
+	}
+}
+
+func TestRunCLICommandPipesStdinFile(t *testing.T) {
+	command := `cat`
+	if runtime.GOOS == "windows" {
+		command = `$input`
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.txt"), []byte("from file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCLICommand(api.CLIStepCLICommand{
+		Command:   command,
+		StdinFile: stringPtr("${dir}/input.txt"),
+	}, map[string]string{"dir": dir})
+
+	if result.Err != "" {
+		t.Fatalf("unexpected command error: %s", result.Err)
+	}
+	if result.Stdout != "from file" {
+		t.Fatalf("stdout = %q, want %q", result.Stdout, "from file")
+	}
+}
+
+func TestRunCLICommandReportsStdinErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		command api.CLIStepCLICommand
+		want    string
+	}{
+		{
+			name:    "both stdin and stdinFile",
+			command: api.CLIStepCLICommand{Command: "cat", Stdin: stringPtr("a"), StdinFile: stringPtr("b")},
+			want:    "invalid stdin configuration",
+		},
+		{
+			name:    "missing stdinFile",
+			command: api.CLIStepCLICommand{Command: "cat", StdinFile: stringPtr(filepath.Join(t.TempDir(), "missing.txt"))},
+			want:    "failed to open stdin file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runCLICommand(tt.command, map[string]string{})
+			if !strings.Contains(result.Err, tt.want) {
+				t.Fatalf("command error = %q, want %q", result.Err, tt.want)
+			}
+			if result.ExitCode >= 0 {
+				t.Fatalf("exit code = %d, want internal failure", result.ExitCode)
+			}
+		})
+	}
+}
+
+func TestRunCLICommandSubmitsStderrOnlyWhenTested(t *testing.T) {
+	command := `printf 'oops\n' >&2`
+	if runtime.GOOS == "windows" {
+		command = `[Console]::Error.WriteLine('oops')`
+	}
+
+	untested := runCLICommand(api.CLIStepCLICommand{Command: command}, map[string]string{})
+	if untested.SubmittedStderr != "" {
+		t.Fatalf("submitted stderr = %q, want it omitted without stderr tests", untested.SubmittedStderr)
+	}
+
+	tested := runCLICommand(api.CLIStepCLICommand{
+		Command: command,
+		Tests:   []api.CLICommandTest{{StderrContainsAll: []string{"oops"}}},
+	}, map[string]string{})
+	if tested.SubmittedStderr != "oops" {
+		t.Fatalf("submitted stderr = %q, want %q", tested.SubmittedStderr, "oops")
+	}
+}
+
+func TestRunCLICommandReportsSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are unix-only")
+	}
+
+	result := runCLICommand(api.CLIStepCLICommand{Command: "kill -TERM $$"}, map[string]string{})
+	if result.Outcome != api.CLICommandSignaled || result.Signal != "SIGTERM" {
+		t.Fatalf("outcome = %q, signal = %q, want SIGTERM", result.Outcome, result.Signal)
+	}
+
+	result = runCLICommand(api.CLIStepCLICommand{Command: "exit 3"}, map[string]string{})
+	if result.Outcome != api.CLICommandExited || result.ExitCode != 3 {
+		t.Fatalf("outcome = %q, exit code = %d, want exited with 3", result.Outcome, result.ExitCode)
+	}
+}
+
+func TestRunCLICommandNormalizesOutputBeforeTests(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses printf")
+	}
+
+	command := api.CLIStepCLICommand{
+		Command: `printf '\033[32mStatus: OK\033[0m  \r\n  Cafe\314\201 ID: 42\r\n'`,
+		StdoutVariables: []api.CLICommandStdoutVariable{{
+			Name:  "id",
+			Regex: `ID: (\d+)`,
+		}},
+		Normalize: &api.CLICommandNormalize{
+			StripANSI:         true,
+			NormalizeNewlines: true,
+			TrimLines:         true,
+			CaseInsensitive:   true,
+			UnicodeNFC:        true,
+		},
+		Tests: []api.CLICommandTest{
+			{StdoutContainsAll: []string{"STATUS: ok\ncafé"}},
+		},
+	}
+
+	variables := map[string]string{}
+	result := runCLICommand(command, variables)
+	if result.Stdout != "Status: OK\nCafé ID: 42" {
+		t.Fatalf("stdout = %q", result.Stdout)
+	}
+	if variables["id"] != "42" {
+		t.Fatalf("id = %q, want variable parsed from normalized stdout", variables["id"])
+	}
+	if failure := evaluateCLICommandTests(0, command, result); failure != nil {
+		t.Fatalf("unexpected failure: %#v", failure)
+	}
+}
+
+func TestCaseInsensitiveCommandKeepsOutputCase(t *testing.T) {
+	command := api.CLIStepCLICommand{
+		Command: `echo '{"userId":"AbC"}'`,
+		StdoutVariables: []api.CLICommandStdoutVariable{
+			{Name: "regexID", Regex: `"userId":"(\w+)"`},
+			{Name: "jqID", Path: ".userId"},
+		},
+		Normalize: &api.CLICommandNormalize{CaseInsensitive: true},
+		Tests: []api.CLICommandTest{
+			{StdoutContainsAll: []string{"USERID"}},
+			{StdoutLines: &api.StdoutLinesTest{Line: intPtr(1), Equals: stringPtr(`{"USERID":"abc"}`)}},
+			{StdoutLines: &api.StdoutLinesTest{Line: intPtr(1), Matches: stringPtr(`USERID`)}},
+			{StdoutLines: &api.StdoutLinesTest{InOrder: []string{"abc"}}},
+			{StdoutJq: &api.StdoutJqTest{
+				InputMode:       "json",
+				Query:           ".userId",
+				ExpectedResults: []api.JqExpectedResult{{Type: api.JqTypeString, Operator: "==", Value: "ABC"}},
+			}},
+			{StdoutSnapshot: &api.StdoutSnapshotTest{Golden: `{"USERID":"ABC"}`}},
+		},
+	}
+
+	variables := map[string]string{}
+	result := runCLICommand(command, variables)
+	if !strings.Contains(result.Stdout, `{"userId":"AbC"}`) {
+		t.Fatalf("stdout = %q, want the original case", result.Stdout)
+	}
+	if variables["regexID"] != "AbC" || variables["jqID"] != "AbC" {
+		t.Fatalf("variables = %v, want both captured in the original case", variables)
+	}
+	if failure := evaluateCLICommandTests(0, command, result); failure != nil {
+		t.Fatalf("unexpected failure: %#v", failure)
+	}
+}
+
+func TestRunCLICommandRecordsResourceUsage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("rusage is unix-only")
+	}
+
+	maxRSS := int64(1 << 40)
+	command := api.CLIStepCLICommand{
+		Command: "sleep 0.1",
+		Tests: []api.CLICommandTest{
+			{MaxDurationMs: intPtr(10_000)},
+			{MaxRSSBytes: &maxRSS},
+		},
+	}
+	result := runCLICommand(command, map[string]string{})
+	if result.DurationMs < 100 {
+		t.Fatalf("duration = %dms, want at least 100ms", result.DurationMs)
+	}
+	if result.MaxRSSBytes <= 0 {
+		t.Fatalf("max RSS = %d, want a measurement", result.MaxRSSBytes)
+	}
+	if failure := evaluateCLICommandTests(0, command, result); failure != nil {
+		t.Fatalf("unexpected failure: %#v", failure)
+	}
+
+	command.Tests = []api.CLICommandTest{{MaxDurationMs: intPtr(10)}}
+	failure := evaluateCLICommandTests(0, command, result)
+	if failure == nil || !strings.Contains(failure.ErrorMessage, "expected command to finish within 10ms") {
+		t.Fatalf("failure = %#v, want duration failure", failure)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		512:              "512 B",
+		2048:             "2.0 KiB",
+		5 * 1024 * 1024:  "5.0 MiB",
+		3 << 30:          "3.0 GiB",
+		1536 * 1024 * 10: "15.0 MiB",
+	} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestSplitCommandArgs(t *testing.T) {
+	args, err := splitCommandArgs(`printf '%s|%s' "two words" it\'s`)
+	if err != nil {
+		t.Fatalf("splitCommandArgs() error = %v", err)
+	}
+	want := []string{"printf", "%s|%s", "two words", "it's"}
+	if !slices.Equal(args, want) {
+		t.Fatalf("args = %q, want %q", args, want)
+	}
+
+	if _, err := splitCommandArgs(`echo "unterminated`); err == nil {
+		t.Fatal("expected unterminated quote error")
+	}
+}
+
+func TestRunCLICommandUsesConfiguredShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix shells")
+	}
+
+	result := runCLICommand(api.CLIStepCLICommand{
+		Command: `echo '$HOME' "${name}"`,
+		Shell:   api.CLIShellExec,
+	}, map[string]string{"name": "boots"})
+	if result.Err != "" || result.Stdout != "$HOME boots" {
+		t.Fatalf("exec result = %#v, want argv run without expansion", result)
+	}
+
+	if _, err := exec.LookPath("bash"); err == nil {
+		result = runCLICommand(api.CLIStepCLICommand{
+			Command: `arr=(a b c); [[ ${#arr[@]} -eq 3 ]] && echo "${arr[1]}"`,
+			Shell:   api.CLIShellBash,
+		}, map[string]string{})
+		if result.Stdout != "b" {
+			t.Fatalf("bash stdout = %q, want b", result.Stdout)
+		}
+	}
+
+	result = runCLICommand(api.CLIStepCLICommand{Command: "echo hi", Shell: "fish"}, map[string]string{})
+	if result.Outcome != api.CLICommandInternalFailure || !strings.Contains(result.Err, `unsupported shell "fish"`) {
+		t.Fatalf("result = %#v, want unsupported shell failure", result)
+	}
+}
+
+func TestApplyShellOverrideKeepsExplicitShells(t *testing.T) {
+	data := api.CLIData{Steps: []api.CLIStep{
+		{CLICommand: &api.CLIStepCLICommand{Command: "echo a"}},
+		{CLICommand: &api.CLIStepCLICommand{Command: "echo b", Shell: api.CLIShellSh}},
+	}}
+	if err := ApplyShellOverride(data, api.CLIShellZsh); err != nil {
+		t.Fatalf("ApplyShellOverride() error = %v", err)
+	}
+	if data.Steps[0].CLICommand.Shell != api.CLIShellZsh || data.Steps[1].CLICommand.Shell != api.CLIShellSh {
+		t.Fatalf("shells = %q, %q", data.Steps[0].CLICommand.Shell, data.Steps[1].CLICommand.Shell)
+	}
+	if err := ApplyShellOverride(data, "fish"); err == nil {
+		t.Fatal("expected unsupported shell error")
+	}
+}
+
+func TestCLIChecksUsesLessonCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	data := api.CLIData{
+		CommandTimeoutMs: intPtr(100),
+		Steps: []api.CLIStep{
+			{CLICommand: &api.CLIStepCLICommand{Command: "sleep 5"}},
+			{CLICommand: &api.CLIStepCLICommand{Command: "echo hi", TimeoutMs: intPtr(5000)}},
+		},
+	}
+
+	results, err := CLIChecks(data, "", 0, func(tea.Msg) {})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+	if result := results[0].CLICommandResult; result.Outcome != api.CLICommandTimedOut || CLICommandTimeout(result.Command) != 100*time.Millisecond {
+		t.Fatalf("result = %#v, want a timeout after the lesson's 100ms", result)
+	}
+	if got := CLICommandTimeout(results[1].CLICommandResult.Command); got != 5*time.Second {
+		t.Fatalf("timeout = %s, want the step's own 5s", got)
+	}
+}
+
+func TestParseStdoutVariablesFromJqPathAndNamedGroups(t *testing.T) {
+	variables := map[string]string{}
+	err := parseStdoutVariables(`{"user":{"id":42,"name":"boots"}}`, []api.CLICommandStdoutVariable{
+		{Name: "userID", Path: ".user.id"},
+		{Name: "missing", Path: ".user.email"},
+		{Regex: `"id":(?P<rawID>\d+),"name":"(?P<rawName>[a-z]+)"`},
+	}, variables)
+	if err != nil {
+		t.Fatalf("parseStdoutVariables() error = %v", err)
+	}
+
+	want := map[string]string{"userID": "42", "rawID": "42", "rawName": "boots"}
+	if !maps.Equal(variables, want) {
+		t.Fatalf("variables = %v, want %v", variables, want)
+	}
+}
+
+func TestParseStdoutVariablesRequired(t *testing.T) {
+	tests := []struct {
+		name    string
+		vardef  api.CLICommandStdoutVariable
+		wantErr string
+	}{
+		{
+			name:    "regex",
+			vardef:  api.CLICommandStdoutVariable{Name: "token", Regex: `token=(\w+)`, Required: true},
+			wantErr: "required stdout variable token was not found",
+		},
+		{
+			name:    "named groups",
+			vardef:  api.CLICommandStdoutVariable{Regex: `(?P<user>\w+)@(?P<host>\w+)`, Required: true},
+			wantErr: "required stdout variable user, host was not found",
+		},
+		{
+			name:    "path",
+			vardef:  api.CLICommandStdoutVariable{Name: "token", Path: ".token", Required: true},
+			wantErr: "required stdout variable token was not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseStdoutVariables(`{"id": 1}`, []api.CLICommandStdoutVariable{tt.vardef}, map[string]string{})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
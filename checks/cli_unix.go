@@ -0,0 +1,43 @@
+//go:build !windows
+
+package checks
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// killProcessGroupOnCancel starts the command in its own process group so a
+// timeout kills everything `sh -c` spawned, not just the shell itself.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// exitSignal returns the name of the signal that killed the process, if any.
+func exitSignal(ee *exec.ExitError) string {
+	status, ok := ee.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	return unix.SignalName(status.Signal())
+}
+
+// maxRSSBytes reads the peak resident set size from rusage. Linux reports it in
+// kilobytes and macOS in bytes.
+func maxRSSBytes(state *os.ProcessState) int64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	if runtime.GOOS == "darwin" {
+		return int64(usage.Maxrss)
+	}
+	return int64(usage.Maxrss) * 1024
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package checks
+
+import (
+	"os"
+	"os/exec"
+)
+
+// killProcessGroupOnCancel keeps the default behavior on Windows, where
+// exec.CommandContext already kills the process on cancel.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
+
+// exitSignal always returns an empty string because Windows processes don't
+// exit by signal.
+func exitSignal(ee *exec.ExitError) string {
+	return ""
+}
+
+// maxRSSBytes returns 0 because Windows doesn't expose rusage.
+func maxRSSBytes(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,153 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+
+	// above this many line pairs the LCS table gets too big, so we only
+	// report the first differing line
+	maxDiffCells = 4_000_000
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns a line-based unified diff from expected to actual, or an
+// empty string when they're equal.
+func unifiedDiff(expected, actual string) string {
+	if expected == actual {
+		return ""
+	}
+
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+	if len(a)*len(b) > maxDiffCells {
+		return firstLineDifference(a, b)
+	}
+
+	ops := diffLines(a, b)
+
+	var str strings.Builder
+	str.WriteString("--- expected\n+++ actual\n")
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		// grow the hunk until there's a long enough run of unchanged lines
+		hunkStart := max(start-diffContextLines, 0)
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContextLines {
+				end = min(end+diffContextLines, len(ops))
+				break
+			}
+			end = run
+		}
+
+		aLine, bLine := hunkLineNumbers(ops, hunkStart)
+		aCount, bCount := 0, 0
+		for _, op := range ops[hunkStart:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&str, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, op := range ops[hunkStart:end] {
+			str.WriteByte(op.kind)
+			str.WriteString(op.line)
+			str.WriteByte('\n')
+		}
+		start = end
+	}
+
+	return strings.TrimRight(str.String(), "\n")
+}
+
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+func hunkLineNumbers(ops []diffOp, index int) (int, int) {
+	aLine, bLine := 1, 1
+	for _, op := range ops[:index] {
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+	}
+	return aLine, bLine
+}
+
+func firstLineDifference(a, b []string) string {
+	for i := range max(len(a), len(b)) {
+		var want, got string
+		if i < len(a) {
+			want = a[i]
+		}
+		if i < len(b) {
+			got = b[i]
+		}
+		if i >= len(a) || i >= len(b) || want != got {
+			return fmt.Sprintf("first difference at line %d:\n-%s\n+%s", i+1, want, got)
+		}
+	}
+	return ""
+}
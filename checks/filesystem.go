@@ -0,0 +1,144 @@
+package checks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+func runFilesystemChecks(filesystem api.CLIStepFilesystem, variables map[string]string) api.FilesystemResult {
+	result := api.FilesystemResult{
+		Entries:    make([]api.FilesystemEntry, 0, len(filesystem.Tests)),
+		Filesystem: filesystem,
+		Variables:  maps.Clone(variables),
+	}
+	for _, test := range filesystem.Tests {
+		path := InterpolateVariables(test.Path, variables)
+		result.Entries = append(result.Entries, inspectPath(path, test))
+	}
+	return result
+}
+
+// inspectPath records what the test needs to know about path. Contents are only
+// read when the test checks them, so unrelated files aren't submitted.
+func inspectPath(path string, test api.FilesystemTest) api.FilesystemEntry {
+	entry := api.FilesystemEntry{Path: path}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entry
+	}
+	if err != nil {
+		entry.Err = err.Error()
+		return entry
+	}
+	entry.Exists = true
+	entry.IsDir = info.IsDir()
+	entry.Mode = formatFileMode(info.Mode())
+
+	if entry.IsDir {
+		if test.DirEntries != nil {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				entry.Err = err.Error()
+				return entry
+			}
+			for _, dirEntry := range entries {
+				name := dirEntry.Name()
+				if dirEntry.IsDir() {
+					name += "/"
+				}
+				entry.DirEntries = append(entry.DirEntries, name)
+			}
+		}
+		return entry
+	}
+
+	if test.SHA256 != nil {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			entry.Err = err.Error()
+			return entry
+		}
+		entry.SHA256 = sum
+	}
+	if test.ContentsContain != nil || test.ContentsRegex != nil {
+		contents, err := readFileCapped(path, maxCLIOutputBytesPerStream)
+		if err != nil {
+			entry.Err = err.Error()
+			return entry
+		}
+		entry.Contents = contents
+	}
+
+	return entry
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func readFileCapped(path string, limit int) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	contents, err := io.ReadAll(io.LimitReader(file, int64(limit)))
+	if err != nil {
+		return "", err
+	}
+	return string(trimIncompleteUTF8(contents)), nil
+}
+
+func formatFileMode(mode fs.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+func prettyPrintFilesystemTest(test api.FilesystemTest, variables map[string]string) string {
+	path := InterpolateVariables(test.Path, variables)
+
+	switch {
+	case test.Exists != nil:
+		if *test.Exists {
+			return fmt.Sprintf("Expect %s to exist", path)
+		}
+		return fmt.Sprintf("Expect %s to not exist", path)
+	case test.ContentsContain != nil:
+		return fmt.Sprintf("Expect %s to contain '%s'", path, InterpolateVariables(*test.ContentsContain, variables))
+	case test.ContentsRegex != nil:
+		return fmt.Sprintf("Expect %s to match '%s'", path, InterpolateVariables(*test.ContentsRegex, variables))
+	case test.SHA256 != nil:
+		return fmt.Sprintf("Expect %s to have sha256 %s", path, InterpolateVariables(*test.SHA256, variables))
+	case test.Mode != nil:
+		return fmt.Sprintf("Expect %s to have mode %s", path, *test.Mode)
+	case test.DirEntries != nil:
+		var str strings.Builder
+		fmt.Fprintf(&str, "Expect %s to contain exactly:", path)
+		for _, name := range test.DirEntries {
+			fmt.Fprintf(&str, "\n      - '%s'", InterpolateVariables(name, variables))
+		}
+		return str.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,76 @@
+package checks
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+func TestFilesystemChecksPass(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello world\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []api.FilesystemTest{
+		{Path: "${dir}/notes.txt", Exists: boolPtr(true)},
+		{Path: "${dir}/missing.txt", Exists: boolPtr(false)},
+		{Path: "${dir}/notes.txt", ContentsContain: stringPtr("hello")},
+		{Path: "${dir}/notes.txt", ContentsRegex: stringPtr(`(?m)^hello \w+$`)},
+		{Path: "${dir}/notes.txt", SHA256: stringPtr("a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447")},
+		{Path: "${dir}", DirEntries: []string{"notes.txt", ".git/"}},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, api.FilesystemTest{Path: "${dir}/notes.txt", Mode: stringPtr("644")})
+	}
+
+	filesystem := api.CLIStepFilesystem{Tests: tests}
+	result := runFilesystemChecks(filesystem, map[string]string{"dir": dir})
+
+	if failure := evaluateFilesystemTests(0, filesystem, result); failure != nil {
+		t.Fatalf("unexpected failure: %#v", failure)
+	}
+	if result.Entries[1].Contents != "" {
+		t.Fatal("existence check unexpectedly read file contents")
+	}
+}
+
+func TestFilesystemChecksReportFailures(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(path, []byte("actual"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		test api.FilesystemTest
+		want string
+	}{
+		{name: "absent", test: api.FilesystemTest{Path: path, Exists: boolPtr(false)}, want: "to not exist"},
+		{name: "missing", test: api.FilesystemTest{Path: path + ".bak", ContentsContain: stringPtr("x")}, want: "to exist"},
+		{name: "contents", test: api.FilesystemTest{Path: path, ContentsContain: stringPtr("expected")}, want: `to contain "expected"`},
+		{name: "hash", test: api.FilesystemTest{Path: path, SHA256: stringPtr("abc")}, want: "to have sha256 abc"},
+		{name: "listing", test: api.FilesystemTest{Path: dir, DirEntries: []string{"other.txt"}}, want: "to contain exactly [other.txt], got [out.txt]"},
+		{name: "not a directory", test: api.FilesystemTest{Path: path, DirEntries: []string{}}, want: "to be a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filesystem := api.CLIStepFilesystem{Tests: []api.FilesystemTest{tt.test}}
+			result := runFilesystemChecks(filesystem, map[string]string{})
+
+			failure := evaluateFilesystemTests(2, filesystem, result)
+			if failure == nil || failure.FailedStepIndex != 2 || !strings.Contains(failure.ErrorMessage, tt.want) {
+				t.Fatalf("failure = %#v, want error containing %q", failure, tt.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,16 @@ package checks
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"maps"
 	"net/http"
 	"net/url"
+	"os"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -31,55 +34,11 @@ func runHTTPRequest(
 ) (
 	result api.HTTPRequestResult,
 ) {
-	finalBaseURL := strings.TrimSuffix(baseURL, "/")
-	interpolatedURL := InterpolateVariables(requestStep.Request.FullURL, variables)
-	completeURL := strings.Replace(interpolatedURL, api.BaseURLPlaceholder, finalBaseURL, 1)
-
-	var requestBody io.Reader
-	var contentType string
-	if requestStep.Request.BodyJSON != nil {
-		bodyJSON := interpolateJSONStrings(requestStep.Request.BodyJSON, variables)
-		dat, err := json.Marshal(bodyJSON)
-		if err != nil {
-			return api.HTTPRequestResult{Err: fmt.Sprintf("Failed to marshal request body: %s", err)}
-		}
-		requestBody = bytes.NewReader(dat)
-		contentType = "application/json"
-	} else if requestStep.Request.BodyForm != nil {
-		formValues := url.Values{}
-		for key, val := range requestStep.Request.BodyForm {
-			interpolatedVal := InterpolateVariables(val, variables)
-			formValues.Add(key, interpolatedVal)
-		}
-
-		requestBody = strings.NewReader(formValues.Encode())
-		contentType = "application/x-www-form-urlencoded"
-	}
-
-	req, err := http.NewRequest(requestStep.Request.Method, completeURL, requestBody)
+	req, requestBodySummary, err := newHTTPRequest(baseURL, variables, requestStep.Request)
 	if err != nil {
-		return api.HTTPRequestResult{Err: fmt.Sprintf("Failed to create request: %s", err)}
-	}
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
-	}
-
-	for k, v := range requestStep.Request.Headers {
-		req.Header.Add(k, InterpolateVariables(v, variables))
-	}
-
-	if requestStep.Request.BasicAuth != nil {
-		req.SetBasicAuth(requestStep.Request.BasicAuth.Username, requestStep.Request.BasicAuth.Password)
-	}
-
-	requestClient := client
-	if requestStep.Request.FollowRedirects != nil && !*requestStep.Request.FollowRedirects {
-		clientCopy := *client
-		clientCopy.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		}
-		requestClient = &clientCopy
+		return api.HTTPRequestResult{Err: err.Error()}
 	}
+	requestClient := httpClientFor(client, requestStep.Request)
 
 	resp, err := requestClient.Do(req)
 	if err != nil {
@@ -115,6 +74,7 @@ func runHTTPRequest(
 
 	result = api.HTTPRequestResult{
 		StatusCode:       resp.StatusCode,
+		RequestBody:      requestBodySummary,
 		ResponseHeaders:  headers,
 		ResponseTrailers: trailers,
 		BodyString:       bodyString,
@@ -124,24 +84,134 @@ func runHTTPRequest(
 	return result
 }
 
-func interpolateJSONStrings(value any, variables map[string]string) any {
+// newHTTPRequest builds the request with its URL, body and headers interpolated,
+// and returns a summary of the body for local output. The body reader is new
+// every call, so it's safe to send the result once.
+func newHTTPRequest(baseURL string, variables map[string]string, request api.HTTPRequest) (*http.Request, string, error) {
+	body, err := newRequestBody(request, variables)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := newHTTPRequestWithBody(baseURL, variables, request, body)
+	if err != nil {
+		return nil, "", err
+	}
+	var summary string
+	if body != nil {
+		summary = body.summary
+	}
+	return req, summary, nil
+}
+
+// newHTTPRequestWithBody builds the request around a body that's already been
+// built, so it can be sent many times without reading its files again. body is
+// nil for requests without one.
+func newHTTPRequestWithBody(baseURL string, variables map[string]string, request api.HTTPRequest, body *requestBody) (*http.Request, error) {
+	finalBaseURL := strings.TrimSuffix(baseURL, "/")
+	interpolatedURL := InterpolateVariables(request.FullURL, variables)
+	completeURL := strings.Replace(interpolatedURL, api.BaseURLPlaceholder, finalBaseURL, 1)
+
+	var requestBody io.Reader
+	if body != nil {
+		requestBody = bytes.NewReader(body.data)
+	}
+	req, err := http.NewRequest(request.Method, completeURL, requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create request: %s", err)
+	}
+	if body != nil && body.contentType != "" {
+		req.Header.Set("Content-Type", body.contentType)
+	}
+
+	for k, v := range request.Headers {
+		req.Header.Add(k, InterpolateVariables(v, variables))
+	}
+
+	if request.BasicAuth != nil {
+		req.SetBasicAuth(request.BasicAuth.Username, request.BasicAuth.Password)
+	}
+	return req, nil
+}
+
+func httpClientFor(client *http.Client, request api.HTTPRequest) *http.Client {
+	if request.FollowRedirects == nil || *request.FollowRedirects {
+		return client
+	}
+	clientCopy := *client
+	clientCopy.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &clientCopy
+}
+
+// interpolateJSONStrings interpolates every string in a JSON body. A string that
+// is only a typed reference like "${id:int}" becomes a JSON value of that type.
+func interpolateJSONStrings(value any, variables map[string]string) (any, error) {
 	switch value := value.(type) {
 	case string:
-		return InterpolateVariables(value, variables)
+		return interpolateJSONString(value, variables)
 	case []any:
 		interpolated := make([]any, len(value))
 		for i, item := range value {
-			interpolated[i] = interpolateJSONStrings(item, variables)
+			var err error
+			if interpolated[i], err = interpolateJSONStrings(item, variables); err != nil {
+				return nil, err
+			}
 		}
-		return interpolated
+		return interpolated, nil
 	case map[string]any:
 		interpolated := make(map[string]any, len(value))
 		for key, item := range value {
-			interpolated[key] = interpolateJSONStrings(item, variables)
+			var err error
+			if interpolated[key], err = interpolateJSONStrings(item, variables); err != nil {
+				return nil, err
+			}
 		}
-		return interpolated
+		return interpolated, nil
 	default:
-		return value
+		return value, nil
+	}
+}
+
+func interpolateJSONString(value string, variables map[string]string) (any, error) {
+	match := interpolationPattern.FindStringSubmatchIndex(value)
+	if match == nil || match[0] != 0 || match[1] != len(value) {
+		return InterpolateVariables(value, variables), nil
+	}
+	parsed := parseInterpolation(value[match[2]:match[3]])
+	if parsed.valueType == "" {
+		return InterpolateVariables(value, variables), nil
+	}
+
+	resolved, ok := resolveInterpolation(parsed, variables)
+	if !ok {
+		return value, nil
+	}
+	switch parsed.valueType {
+	case interpolationTypeInt:
+		n, err := strconv.ParseInt(strings.TrimSpace(resolved), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s is not an int: %q", parsed.name, resolved)
+		}
+		return n, nil
+	case interpolationTypeNumber:
+		n, err := strconv.ParseFloat(strings.TrimSpace(resolved), 64)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s is not a number: %q", parsed.name, resolved)
+		}
+		return n, nil
+	case interpolationTypeBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(resolved))
+		if err != nil {
+			return nil, fmt.Errorf("variable %s is not a bool: %q", parsed.name, resolved)
+		}
+		return b, nil
+	default:
+		var decoded any
+		if err := json.Unmarshal([]byte(resolved), &decoded); err != nil {
+			return nil, fmt.Errorf("variable %s is not valid JSON: %q", parsed.name, resolved)
+		}
+		return decoded, nil
 	}
 }
 
@@ -242,8 +312,8 @@ func parseVariables(body []byte, vardefs []api.HTTPRequestResponseVariable, vari
 			if err != nil {
 				return err
 			}
-			if len(vals) == 1 && vals[0] != nil {
-				variables[vardef.Name] = fmt.Sprintf("%v", vals[0])
+			if value, ok := jqVariableValue(vals); ok {
+				variables[vardef.Name] = value
 			}
 
 		default:
@@ -293,24 +363,151 @@ func findHeaderValue(headers map[string]string, key string) (string, bool) {
 	return "", false
 }
 
+const (
+	envInterpolationPrefix = "env."
+
+	// lessonEnvPrefix marks environment variables the student set for lessons
+	// to read, since anything a lesson reads can end up in the submission
+	lessonEnvPrefix = "BOOTDEV_"
+)
+
+// allowedEnvVariables can be read with ${env.NAME} without the BOOTDEV_ prefix
+var allowedEnvVariables = []string{"HOME", "USER", "SHELL", "LANG", "TERM"}
+
+// interpolation is a parsed ${...} expression:
+// name[:-default][|filter...][:type]
+type interpolation struct {
+	name       string
+	defaultVal *string
+	filters    []string
+	valueType  string // only changes anything in JSON bodies
+}
+
+const (
+	interpolationTypeInt    = "int"
+	interpolationTypeNumber = "number"
+	interpolationTypeBool   = "bool"
+	interpolationTypeJSON   = "json"
+)
+
+var interpolationFilters = map[string]func(string) string{
+	"base64":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"urlencode": url.QueryEscape,
+	"upper":     strings.ToUpper,
+	"jsonescape": func(s string) string {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(s); err != nil {
+			return s
+		}
+		encoded := strings.TrimSuffix(buf.String(), "\n")
+		return encoded[1 : len(encoded)-1]
+	},
+}
+
+func parseInterpolation(expr string) interpolation {
+	var valueType string
+	if i := strings.LastIndex(expr, ":"); i >= 0 {
+		switch suffix := expr[i+1:]; suffix {
+		case interpolationTypeInt, interpolationTypeNumber, interpolationTypeBool, interpolationTypeJSON:
+			expr, valueType = expr[:i], suffix
+		}
+	}
+
+	// filters are peeled off the end, so a default can contain a "|" as long
+	// as the text after it isn't a filter name
+	parsed := interpolation{valueType: valueType}
+	for {
+		i := strings.LastIndex(expr, "|")
+		if i < 0 {
+			break
+		}
+		filter := strings.TrimSpace(expr[i+1:])
+		if _, ok := interpolationFilters[filter]; !ok {
+			break
+		}
+		parsed.filters = append([]string{filter}, parsed.filters...)
+		expr = expr[:i]
+	}
+
+	if name, defaultVal, ok := strings.Cut(expr, ":-"); ok {
+		expr = name
+		parsed.defaultVal = &defaultVal
+	} else if name, rest, ok := strings.Cut(expr, "|"); ok {
+		// unknown filters stay in the list so the expression isn't resolved
+		expr = name
+		var unknown []string
+		for _, filter := range strings.Split(rest, "|") {
+			unknown = append(unknown, strings.TrimSpace(filter))
+		}
+		parsed.filters = append(unknown, parsed.filters...)
+	}
+	parsed.name = strings.TrimSpace(expr)
+	return parsed
+}
+
+// InterpolateVariables replaces ${name} with the variable's value, falling back
+// to the default in ${name:-default} when it's unset or empty like the shell
+// does, and then applying any |filters. Names starting with "env." read the
+// process environment, limited to BOOTDEV_ variables and a few harmless ones,
+// and a trailing :type only matters in JSON bodies. Expressions that can't be
+// resolved are left as they are.
 func InterpolateVariables(template string, vars map[string]string) string {
 	return interpolationPattern.ReplaceAllStringFunc(template, func(m string) string {
-		// Extract the key from the match, which is in the form ${key}
-		key := strings.TrimSuffix(strings.TrimPrefix(m, "${"), "}")
-		if val, ok := vars[key]; ok {
+		// Extract the expression from the match, which is in the form ${expr}
+		expr := interpolationPattern.FindStringSubmatch(m)[1]
+		if val, ok := resolveInterpolation(parseInterpolation(expr), vars); ok {
 			return val
 		}
 		return m
 	})
 }
 
+func resolveInterpolation(parsed interpolation, vars map[string]string) (string, bool) {
+	val, ok := lookupInterpolation(parsed.name, vars)
+	if parsed.defaultVal != nil && val == "" {
+		val, ok = *parsed.defaultVal, true
+	}
+	if !ok {
+		return "", false
+	}
+	for _, name := range parsed.filters {
+		filter, ok := interpolationFilters[name]
+		if !ok {
+			return "", false
+		}
+		val = filter(val)
+	}
+	return val, true
+}
+
+func lookupInterpolation(name string, vars map[string]string) (string, bool) {
+	if envName, ok := strings.CutPrefix(name, envInterpolationPrefix); ok {
+		if !strings.HasPrefix(envName, lessonEnvPrefix) && !slices.Contains(allowedEnvVariables, envName) {
+			return "", false
+		}
+		return os.LookupEnv(envName)
+	}
+	val, ok := vars[name]
+	return val, ok
+}
+
+// InterpolationNames returns the variable names a template refers to, without
+// defaults or filters. Environment lookups aren't lesson variables, so they're
+// left out.
 func InterpolationNames(template string) []string {
 	matches := interpolationPattern.FindAllStringSubmatch(template, -1)
 	names := make([]string, 0, len(matches))
 	for _, match := range matches {
-		if len(match) > 1 {
-			names = append(names, match[1])
+		if len(match) < 2 {
+			continue
+		}
+		name := parseInterpolation(match[1]).name
+		if strings.HasPrefix(name, envInterpolationPrefix) {
+			continue
 		}
+		names = append(names, name)
 	}
 	return names
 }
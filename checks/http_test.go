@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 
@@ -44,6 +45,92 @@ func TestInterpolateVariables(t *testing.T) {
 	}
 }
 
+func TestInterpolateVariablesDefaultsFiltersAndEnv(t *testing.T) {
+	t.Setenv("BOOTDEV_TEST_HOME", "/home/boots")
+	t.Setenv("SECRET_TEST_TOKEN", "hunter2")
+	vars := map[string]string{
+		"token": "user:pass",
+		"q":     "a b&c",
+		"name":  "boots",
+		"x":     `say "hi" <b>`,
+		"empty": "",
+	}
+
+	tests := map[string]string{
+		"${missing:-guest}":            "guest",
+		"${name:-guest}":               "boots",
+		"${empty:-guest}":              "guest",
+		"${empty}":                     "",
+		"${missing:-}":                 "",
+		"${token|base64}":              "dXNlcjpwYXNz",
+		"${q|urlencode}":               "a+b%26c",
+		"${name|upper}":                "BOOTS",
+		"${x|jsonescape}":              `say \"hi\" <b>`,
+		"${missing:-anon|upper}":       "ANON",
+		"${missing:-a|b}":              "a|b",
+		"${missing:-a|b|upper}":        "A|B",
+		"${name|upper|base64}":         "Qk9PVFM=",
+		"${env.BOOTDEV_TEST_HOME}":     "/home/boots",
+		"${env.BOOTDEV_TEST_UNSET}":    "${env.BOOTDEV_TEST_UNSET}",
+		"${env.BOOTDEV_TEST_UNSET:-/}": "/",
+		"${env.SECRET_TEST_TOKEN}":     "${env.SECRET_TEST_TOKEN}",
+		"${env.SECRET_TEST_TOKEN:-}":   "",
+		"${missing|upper}":             "${missing|upper}",
+		"${name|reverse}":              "${name|reverse}",
+	}
+	for template, want := range tests {
+		if got := InterpolateVariables(template, vars); got != want {
+			t.Errorf("InterpolateVariables(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestInterpolationNamesStripsDefaultsAndFilters(t *testing.T) {
+	got := InterpolationNames("${id:-1}/${token|base64}/${env.HOME}/${q:-x|urlencode}/${n:int}")
+	want := []string{"id", "token", "q", "n"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("InterpolationNames() = %#v, want %#v", got, want)
+	}
+}
+
+func TestInterpolateJSONStringsTypedReferences(t *testing.T) {
+	variables := map[string]string{
+		"id":    "42",
+		"price": "9.5",
+		"flag":  "true",
+		"obj":   `{"tags":["a","b"]}`,
+	}
+	body := map[string]any{
+		"id":      "${id:int}",
+		"price":   "${price:number}",
+		"flag":    "${flag:bool}",
+		"obj":     "${obj:json}",
+		"label":   "user ${id:int}",
+		"plain":   "${id}",
+		"default": "${missing:-7:int}",
+		"missing": "${missing:int}",
+		"list":    []any{"${id:int}"},
+	}
+
+	got, err := interpolateJSONStrings(body, variables)
+	if err != nil {
+		t.Fatalf("interpolateJSONStrings() error = %v", err)
+	}
+	dat, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("failed to marshal body: %v", err)
+	}
+	want := `{"default":7,"flag":true,"id":42,"label":"user 42","list":[42],"missing":"${missing:int}","obj":{"tags":["a","b"]},"plain":"42","price":9.5}`
+	if string(dat) != want {
+		t.Fatalf("body = %s, want %s", dat, want)
+	}
+
+	_, err = interpolateJSONStrings(map[string]any{"id": "${name:int}"}, map[string]string{"name": "boots"})
+	if err == nil || err.Error() != `variable name is not an int: "boots"` {
+		t.Fatalf("error = %v, want int conversion error", err)
+	}
+}
+
 func TestInterpolationNames(t *testing.T) {
 	got := InterpolationNames("${baseURL}/users/${id}/${id}")
 	want := []string{"baseURL", "id", "id"}
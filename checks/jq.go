@@ -12,9 +12,17 @@ import (
 )
 
 func prettyPrintStdoutJqTest(test api.StdoutJqTest, variables map[string]string) string {
+	return prettyPrintJqTest("jq query", test, variables)
+}
+
+func prettyPrintStderrJqTest(test api.StdoutJqTest, variables map[string]string) string {
+	return prettyPrintJqTest("stderr jq query", test, variables)
+}
+
+func prettyPrintJqTest(label string, test api.StdoutJqTest, variables map[string]string) string {
 	queryText := InterpolateVariables(test.Query, variables)
 	var str strings.Builder
-	fmt.Fprintf(&str, "Expect jq query '%s' to yield values satisfying:", queryText)
+	fmt.Fprintf(&str, "Expect %s '%s' to yield values satisfying:", label, queryText)
 	if len(test.ExpectedResults) == 0 {
 		str.WriteString("\n       - [no expected results provided]")
 		return str.String()
@@ -40,20 +48,22 @@ func formatJqExpectedValue(expected api.JqExpectedResult, variables map[string]s
 	return string(encoded)
 }
 
-func collectStdoutJqOutputs(cmd api.CLIStepCLICommand, result api.CLICommandResult) []api.CLICommandJqOutput {
+func collectJqOutputs(cmd api.CLIStepCLICommand, result api.CLICommandResult) []api.CLICommandJqOutput {
 	var outputs []api.CLICommandJqOutput
 	for _, test := range cmd.Tests {
-		if test.StdoutJq == nil {
-			continue
+		if test.StdoutJq != nil {
+			outputs = append(outputs, runJqQuery(result.Stdout, *test.StdoutJq, result.Variables))
+		}
+		if test.StderrJq != nil {
+			outputs = append(outputs, runJqQuery(result.Stderr, *test.StderrJq, result.Variables))
 		}
-		outputs = append(outputs, runStdoutJqQuery(result.Stdout, *test.StdoutJq, result.Variables))
 	}
 	return outputs
 }
 
-func runStdoutJqQuery(stdout string, test api.StdoutJqTest, variables map[string]string) api.CLICommandJqOutput {
+func runJqQuery(output string, test api.StdoutJqTest, variables map[string]string) api.CLICommandJqOutput {
 	queryText := InterpolateVariables(test.Query, variables)
-	input, err := parseJqInput(stdout, test.InputMode)
+	input, err := parseJqInput(output, test.InputMode)
 	if err != nil {
 		return api.CLICommandJqOutput{Query: queryText, Error: err.Error()}
 	}
@@ -185,3 +195,29 @@ func valsFromJqPath(path string, jsn string) ([]any, error) {
 	}
 	return vals, nil
 }
+
+// jqVariableValue turns the values a jq path returned into a variable. Several
+// values are captured as a JSON array, as are single arrays and objects, so
+// that they can be looped over with foreach.
+func jqVariableValue(vals []any) (string, bool) {
+	switch {
+	case len(vals) == 0 || len(vals) == 1 && vals[0] == nil:
+		return "", false
+	case len(vals) > 1:
+		return jsonVariableValue(vals), true
+	default:
+		return jsonVariableValue(vals[0]), true
+	}
+}
+
+func jsonVariableValue(val any) string {
+	switch val := val.(type) {
+	case []any, map[string]any:
+		if dat, err := json.Marshal(val); err == nil {
+			return string(dat)
+		}
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", val)
+}
@@ -69,7 +69,7 @@ func TestRunStdoutJqQuery(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := runStdoutJqQuery(tt.stdout, tt.test, tt.variables)
+			got := runJqQuery(tt.stdout, tt.test, tt.variables)
 			if tt.wantError {
 				if got.Query != tt.want.Query {
 					t.Fatalf("Query = %q, want %q", got.Query, tt.want.Query)
@@ -80,7 +80,7 @@ func TestRunStdoutJqQuery(t *testing.T) {
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Fatalf("runStdoutJqQuery() = %#v, want %#v", got, tt.want)
+				t.Fatalf("runJqQuery() = %#v, want %#v", got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,158 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+// evaluateStdoutLines returns the 1-based stdout line the failure is about, or
+// 0 when it's about the output as a whole.
+func evaluateStdoutLines(stdout string, test api.StdoutLinesTest, variables map[string]string, caseInsensitive bool) (int, error) {
+	lines := stdoutLines(stdout)
+
+	switch {
+	case test.Count != nil:
+		if len(lines) != *test.Count {
+			return firstExtraLine(len(lines), *test.Count),
+				fmt.Errorf("expected stdout to have exactly %d lines, got %d", *test.Count, len(lines))
+		}
+		return 0, nil
+
+	case test.CountLT != nil:
+		if len(lines) >= *test.CountLT {
+			return firstExtraLine(len(lines), *test.CountLT-1),
+				fmt.Errorf("expected stdout to have fewer than %d lines, got %d", *test.CountLT, len(lines))
+		}
+		return 0, nil
+
+	case len(test.InOrder) > 0:
+		next := 0
+		for _, needle := range test.InOrder {
+			needle = InterpolateVariables(needle, variables)
+			found := indexOfLineContaining(lines[next:], needle, caseInsensitive)
+			if found >= 0 {
+				next += found + 1
+				continue
+			}
+			if earlier := indexOfLineContaining(lines[:next], needle, caseInsensitive); earlier >= 0 {
+				return earlier + 1, fmt.Errorf("expected a line containing %q after line %d, found it on line %d", needle, next, earlier+1)
+			}
+			return 0, fmt.Errorf("expected a line containing %q", needle)
+		}
+		return 0, nil
+
+	case test.Line != nil && (test.Equals != nil || test.Matches != nil):
+		index := *test.Line - 1
+		if *test.Line < 0 {
+			index = len(lines) + *test.Line
+		}
+		if *test.Line == 0 || index < 0 || index >= len(lines) {
+			return 0, fmt.Errorf("expected stdout to have %s, got %d lines", describeStdoutLine(*test.Line), len(lines))
+		}
+		line := lines[index]
+
+		if test.Equals != nil {
+			want := InterpolateVariables(*test.Equals, variables)
+			if foldCase(line, caseInsensitive) != foldCase(want, caseInsensitive) {
+				return index + 1, fmt.Errorf("expected line %d to equal %q, got %q", index+1, want, line)
+			}
+			return 0, nil
+		}
+		pattern := InterpolateVariables(*test.Matches, variables)
+		flags := ""
+		if caseInsensitive {
+			flags = "(?i)"
+		}
+		re, err := regexp.Compile(flags + pattern)
+		if err != nil {
+			return 0, fmt.Errorf("invalid stdoutLines regex %q: %w", pattern, err)
+		}
+		if !re.MatchString(line) {
+			return index + 1, fmt.Errorf("expected line %d to match %q, got %q", index+1, pattern, line)
+		}
+		return 0, nil
+
+	default:
+		return 0, fmt.Errorf("invalid stdoutLines configuration")
+	}
+}
+
+// StdoutLinesFailureLine returns the stdout line a failed stdoutLines test is
+// about, so the renderer can point at it. It returns 0 if there isn't one.
+func StdoutLinesFailureLine(result api.CLICommandResult, test api.StdoutLinesTest) int {
+	line, _ := evaluateStdoutLines(result.Stdout, test, result.Variables, isCaseInsensitive(result.Command.Normalize))
+	return line
+}
+
+func stdoutLines(stdout string) []string {
+	if stdout == "" {
+		return nil
+	}
+	return strings.Split(stdout, "\n")
+}
+
+// firstExtraLine points at the first line past limit when there are too many
+// lines, and at nothing when there are too few.
+func firstExtraLine(lineCount, limit int) int {
+	if lineCount > limit {
+		return max(limit, 0) + 1
+	}
+	return 0
+}
+
+func indexOfLineContaining(lines []string, needle string, caseInsensitive bool) int {
+	needle = foldCase(needle, caseInsensitive)
+	for i, line := range lines {
+		if strings.Contains(foldCase(line, caseInsensitive), needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+func describeStdoutLine(line int) string {
+	switch {
+	case line == -1:
+		return "a last line"
+	case line < 0:
+		return fmt.Sprintf("a line %d from the end", -line)
+	default:
+		return fmt.Sprintf("a line %d", line)
+	}
+}
+
+func prettyPrintStdoutLinesTest(test api.StdoutLinesTest, variables map[string]string) string {
+	switch {
+	case test.Count != nil:
+		return fmt.Sprintf("Expect stdout to have exactly %d lines", *test.Count)
+	case test.CountLT != nil:
+		return fmt.Sprintf("Expect stdout to have fewer than %d lines", *test.CountLT)
+	case len(test.InOrder) > 0:
+		var str strings.Builder
+		str.WriteString("Expect stdout lines in order:")
+		for _, needle := range test.InOrder {
+			fmt.Fprintf(&str, "\n      - '%s'", InterpolateVariables(needle, variables))
+		}
+		return str.String()
+	case test.Line != nil && test.Equals != nil:
+		return fmt.Sprintf("Expect %s to equal '%s'", prettyPrintStdoutLine(*test.Line), InterpolateVariables(*test.Equals, variables))
+	case test.Line != nil && test.Matches != nil:
+		return fmt.Sprintf("Expect %s to match '%s'", prettyPrintStdoutLine(*test.Line), InterpolateVariables(*test.Matches, variables))
+	default:
+		return ""
+	}
+}
+
+func prettyPrintStdoutLine(line int) string {
+	switch {
+	case line == -1:
+		return "the last stdout line"
+	case line < 0:
+		return fmt.Sprintf("stdout line %d from the end", -line)
+	default:
+		return fmt.Sprintf("stdout line %d", line)
+	}
+}
@@ -0,0 +1,264 @@
+package checks
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+const (
+	maxHTTPLoadRequests    = 10_000
+	maxHTTPLoadConcurrency = 256
+	maxHTTPLoadRate        = 10_000 // requests per second
+	httpLoadHistogramSize  = 8
+)
+
+// runHTTPLoad sends the load step's requests from a pool of Concurrency
+// workers, pacing their starts when RatePerSecond is set. Every request is
+// built with the same variables, like a regular HTTP request step, but the body
+// is only built once and shared by all of them.
+func runHTTPLoad(client *http.Client, baseURL string, load api.CLIStepHTTPLoad, variables map[string]string) api.HTTPLoadResult {
+	result := api.HTTPLoadResult{
+		StatusCodes: map[int]int{},
+		Variables:   maps.Clone(variables),
+		Load:        load,
+	}
+	if load.Requests < 1 || load.Requests > maxHTTPLoadRequests {
+		result.Err = fmt.Sprintf("httpLoad requests must be between 1 and %d", maxHTTPLoadRequests)
+		return result
+	}
+	// written so NaN fails too
+	if !(load.RatePerSecond >= 0 && load.RatePerSecond <= maxHTTPLoadRate) {
+		result.Err = fmt.Sprintf("httpLoad ratePerSecond must be between 0 and %d", maxHTTPLoadRate)
+		return result
+	}
+	body, err := newRequestBody(load.Request, variables)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	if _, err := newHTTPRequestWithBody(baseURL, variables, load.Request, body); err != nil {
+		result.Err = err.Error()
+		return result
+	}
+
+	concurrency := min(max(load.Concurrency, 1), maxHTTPLoadConcurrency, load.Requests)
+	loadClient := httpLoadClient(httpClientFor(client, load.Request), concurrency)
+	defer loadClient.CloseIdleConnections()
+
+	jobs := make(chan struct{})
+	var mu sync.Mutex
+	var latencies []float64
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				statusCode, latency, err := sendLoadRequest(loadClient, baseURL, variables, load.Request, body)
+				mu.Lock()
+				if err != nil {
+					result.Errors++
+				} else {
+					result.StatusCodes[statusCode]++
+					latencies = append(latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	start := time.Now()
+	var ticker *time.Ticker
+	if load.RatePerSecond > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / load.RatePerSecond))
+		defer ticker.Stop()
+	}
+	for i := range load.Requests {
+		if ticker != nil && i > 0 {
+			<-ticker.C
+		}
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+
+	result.Requests = load.Requests
+	result.DurationMs = int(time.Since(start).Milliseconds())
+	slices.Sort(latencies)
+	result.P50Ms = percentile(latencies, 50)
+	result.P95Ms = percentile(latencies, 95)
+	result.P99Ms = percentile(latencies, 99)
+	if len(latencies) > 0 {
+		result.MaxMs = latencies[len(latencies)-1]
+	}
+	result.Histogram = latencyHistogram(latencies, httpLoadHistogramSize)
+	return result
+}
+
+// httpLoadClient keeps enough idle connections around for every worker, so the
+// test measures the server rather than connection setup.
+func httpLoadClient(client *http.Client, concurrency int) *http.Client {
+	transport, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		return client
+	}
+	transport = transport.Clone()
+	transport.MaxIdleConnsPerHost = concurrency
+	clientCopy := *client
+	clientCopy.Transport = transport
+	return &clientCopy
+}
+
+func sendLoadRequest(
+	client *http.Client,
+	baseURL string,
+	variables map[string]string,
+	request api.HTTPRequest,
+	body *requestBody,
+) (int, float64, error) {
+	req, err := newHTTPRequestWithBody(baseURL, variables, request, body)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPResponseBodyBytes)); err != nil {
+		return 0, 0, err
+	}
+	return resp.StatusCode, float64(time.Since(start).Microseconds()) / 1000, nil
+}
+
+// percentile uses the nearest-rank method on sorted latencies
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+func latencyHistogram(sorted []float64, size int) []api.HTTPLoadBucket {
+	if len(sorted) == 0 {
+		return nil
+	}
+	maxMs := sorted[len(sorted)-1]
+	buckets := make([]api.HTTPLoadBucket, size)
+	for i := range buckets {
+		buckets[i].UpToMs = maxMs * float64(i+1) / float64(size)
+	}
+	for _, latency := range sorted {
+		i := size - 1
+		if maxMs > 0 {
+			i = min(int(latency/maxMs*float64(size)), size-1)
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+func evaluateHTTPLoadTests(stepIndex int, load api.CLIStepHTTPLoad, result api.HTTPLoadResult) *api.StructuredErrCLI {
+	if result.Err != "" {
+		return localFailure(stepIndex, 0, result.Err)
+	}
+	errorsAllowed := slices.ContainsFunc(load.Tests, func(test api.HTTPLoadTest) bool {
+		return test.MaxErrors != nil
+	})
+	for testIndex, test := range load.Tests {
+		if err := evaluateHTTPLoadTest(test, result, errorsAllowed); err != nil {
+			return localFailure(stepIndex, testIndex, err.Error())
+		}
+	}
+	return nil
+}
+
+// evaluateHTTPLoadTest fails latency checks when no request got a response, or
+// when some failed and no maxErrors test allows it, since the percentiles only
+// cover requests that got a response.
+func evaluateHTTPLoadTest(test api.HTTPLoadTest, result api.HTTPLoadResult, errorsAllowed bool) error {
+	latencyTests := []struct {
+		name  string
+		maxMs *int
+		got   float64
+	}{
+		{"p50", test.P50MaxMs, result.P50Ms},
+		{"p95", test.P95MaxMs, result.P95Ms},
+		{"p99", test.P99MaxMs, result.P99Ms},
+	}
+	for _, latency := range latencyTests {
+		if latency.maxMs == nil {
+			continue
+		}
+		if result.Errors >= result.Requests {
+			return fmt.Errorf("expected %s latency of at most %dms, but no request got a response", latency.name, *latency.maxMs)
+		}
+		if result.Errors > 0 && !errorsAllowed {
+			return fmt.Errorf("expected %s latency of at most %dms, but %d requests failed", latency.name, *latency.maxMs, result.Errors)
+		}
+		if latency.got > float64(*latency.maxMs) {
+			return fmt.Errorf("expected %s latency of at most %dms, got %.1fms", latency.name, *latency.maxMs, latency.got)
+		}
+	}
+
+	if test.MaxErrors != nil && result.Errors > *test.MaxErrors {
+		return fmt.Errorf("expected at most %d failed requests, got %d", *test.MaxErrors, result.Errors)
+	}
+
+	if test.StatusCode != nil {
+		matched := result.StatusCodes[*test.StatusCode]
+		if !countInRange(matched, result.Requests, test.Exactly, test.AtLeast, test.AtMost) {
+			return fmt.Errorf(
+				"expected %s of %d requests to return status %d, got %d",
+				describeCount(test.Exactly, test.AtLeast, test.AtMost), result.Requests, *test.StatusCode, matched,
+			)
+		}
+	}
+	return nil
+}
+
+func prettyPrintHTTPLoadTest(test api.HTTPLoadTest) string {
+	var parts []string
+	if test.P50MaxMs != nil {
+		parts = append(parts, fmt.Sprintf("Expect p50 latency of at most %dms", *test.P50MaxMs))
+	}
+	if test.P95MaxMs != nil {
+		parts = append(parts, fmt.Sprintf("Expect p95 latency of at most %dms", *test.P95MaxMs))
+	}
+	if test.P99MaxMs != nil {
+		parts = append(parts, fmt.Sprintf("Expect p99 latency of at most %dms", *test.P99MaxMs))
+	}
+	if test.MaxErrors != nil {
+		parts = append(parts, fmt.Sprintf("Expect at most %d failed requests", *test.MaxErrors))
+	}
+	if test.StatusCode != nil {
+		parts = append(parts, fmt.Sprintf(
+			"Expect %s of the requests to return status %d",
+			describeCount(test.Exactly, test.AtLeast, test.AtMost), *test.StatusCode,
+		))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeHTTPLoad(load api.CLIStepHTTPLoad) string {
+	description := fmt.Sprintf("%d requests, %d at a time", load.Requests, max(load.Concurrency, 1))
+	if load.RatePerSecond > 0 {
+		description += fmt.Sprintf(", %g/s", load.RatePerSecond)
+	}
+	return description
+}
@@ -0,0 +1,164 @@
+package checks
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+func TestRunHTTPLoadCollectsStatusesAndLatencies(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		user, pass, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		if user != "admin" || pass != "hunter2" || r.Header.Get("X-Token") != "abc" || string(body) != `{"id":"abc"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if n%4 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	load := api.CLIStepHTTPLoad{
+		Request: api.HTTPRequest{
+			Method:    http.MethodPost,
+			FullURL:   api.BaseURLPlaceholder + "/jobs",
+			Headers:   map[string]string{"X-Token": "${token}"},
+			BodyJSON:  map[string]any{"id": "${token}"},
+			BasicAuth: &api.HTTPBasicAuth{Username: "admin", Password: "hunter2"},
+		},
+		Requests:    20,
+		Concurrency: 5,
+	}
+	result := runHTTPLoad(&http.Client{}, server.URL, load, map[string]string{"token": "abc"})
+
+	if result.Err != "" {
+		t.Fatalf("runHTTPLoad() err = %s", result.Err)
+	}
+	if want := map[int]int{http.StatusOK: 15, http.StatusServiceUnavailable: 5}; !reflect.DeepEqual(result.StatusCodes, want) {
+		t.Fatalf("status codes = %v, want %v", result.StatusCodes, want)
+	}
+	if result.P50Ms <= 0 || result.P50Ms > result.P95Ms || result.P95Ms > result.P99Ms || result.P99Ms > result.MaxMs {
+		t.Fatalf("percentiles = %v/%v/%v max %v, want them ordered", result.P50Ms, result.P95Ms, result.P99Ms, result.MaxMs)
+	}
+	total := 0
+	for _, bucket := range result.Histogram {
+		total += bucket.Count
+	}
+	if total != 20 {
+		t.Fatalf("histogram holds %d requests, want 20", total)
+	}
+
+	load.Tests = []api.HTTPLoadTest{
+		{StatusCode: intPtr(http.StatusOK), AtLeast: intPtr(15)},
+		{MaxErrors: intPtr(0)},
+		{StatusCode: intPtr(http.StatusOK)},
+	}
+	failure := evaluateHTTPLoadTests(0, load, result)
+	if failure == nil || failure.FailedTestIndex != 2 || failure.ErrorMessage != "expected all of 20 requests to return status 200, got 15" {
+		t.Fatalf("failure = %#v, want the all-200 test to fail", failure)
+	}
+}
+
+func TestRunHTTPLoadReadsBodyFileOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(path, []byte(`{"n":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var matched atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// later requests only get the body if it was read before the first one
+		_ = os.Remove(path)
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == `{"n":1}` {
+			matched.Add(1)
+		}
+	}))
+	defer server.Close()
+
+	result := runHTTPLoad(server.Client(), server.URL, api.CLIStepHTTPLoad{
+		Request:  api.HTTPRequest{Method: http.MethodPost, FullURL: api.BaseURLPlaceholder, BodyFile: path},
+		Requests: 4,
+	}, map[string]string{})
+
+	if result.Err != "" || result.Errors != 0 {
+		t.Fatalf("result = %#v, want every request sent", result)
+	}
+	if got := matched.Load(); got != 4 {
+		t.Fatalf("requests with the full body = %d, want 4", got)
+	}
+}
+
+func TestRunHTTPLoadRejectsInvalidRequestCount(t *testing.T) {
+	result := runHTTPLoad(&http.Client{}, "", api.CLIStepHTTPLoad{Requests: 0}, nil)
+	if !strings.Contains(result.Err, "requests must be between 1 and") {
+		t.Fatalf("err = %q, want a request count error", result.Err)
+	}
+}
+
+func TestRunHTTPLoadRejectsInvalidRate(t *testing.T) {
+	for _, rate := range []float64{-1, 1e10, math.Inf(1), math.NaN()} {
+		result := runHTTPLoad(&http.Client{}, "", api.CLIStepHTTPLoad{Requests: 2, RatePerSecond: rate}, nil)
+		if !strings.Contains(result.Err, "ratePerSecond must be between 0 and") {
+			t.Fatalf("rate %v: err = %q, want a rate error", rate, result.Err)
+		}
+	}
+}
+
+func TestPercentileAndHistogram(t *testing.T) {
+	latencies := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if got := percentile(latencies, 50); got != 5 {
+		t.Fatalf("p50 = %v, want 5", got)
+	}
+	if got := percentile(latencies, 95); got != 10 {
+		t.Fatalf("p95 = %v, want 10", got)
+	}
+
+	got := latencyHistogram(latencies, 2)
+	want := []api.HTTPLoadBucket{{UpToMs: 5, Count: 4}, {UpToMs: 10, Count: 6}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("histogram = %#v, want %#v", got, want)
+	}
+}
+
+func TestEvaluateHTTPLoadLatency(t *testing.T) {
+	err := evaluateHTTPLoadTest(api.HTTPLoadTest{P95MaxMs: intPtr(100)}, api.HTTPLoadResult{Requests: 1, P95Ms: 120.5}, false)
+	if err == nil || err.Error() != "expected p95 latency of at most 100ms, got 120.5ms" {
+		t.Fatalf("err = %v, want a p95 failure", err)
+	}
+	if got := prettyPrintHTTPLoadTest(api.HTTPLoadTest{StatusCode: intPtr(429), AtLeast: intPtr(1)}); got != "Expect at least 1 of the requests to return status 429" {
+		t.Fatalf("pretty print = %q", got)
+	}
+}
+
+func TestEvaluateHTTPLoadLatencyWithFailedRequests(t *testing.T) {
+	load := api.CLIStepHTTPLoad{Tests: []api.HTTPLoadTest{{P95MaxMs: intPtr(100)}}}
+
+	failure := evaluateHTTPLoadTests(0, load, api.HTTPLoadResult{Requests: 5, Errors: 5})
+	if failure == nil || failure.ErrorMessage != "expected p95 latency of at most 100ms, but no request got a response" {
+		t.Fatalf("failure = %#v, want a no-response failure", failure)
+	}
+
+	someFailed := api.HTTPLoadResult{Requests: 5, Errors: 1, P95Ms: 10}
+	failure = evaluateHTTPLoadTests(0, load, someFailed)
+	if failure == nil || failure.ErrorMessage != "expected p95 latency of at most 100ms, but 1 requests failed" {
+		t.Fatalf("failure = %#v, want a failed-requests failure", failure)
+	}
+
+	load.Tests = append(load.Tests, api.HTTPLoadTest{MaxErrors: intPtr(1)})
+	if failure := evaluateHTTPLoadTests(0, load, someFailed); failure != nil {
+		t.Fatalf("failure = %#v, want maxErrors to allow the failed request", failure)
+	}
+}
@@ -2,8 +2,11 @@ package checks
 
 import (
 	"fmt"
+	"io/fs"
 	"math"
 	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -34,27 +37,83 @@ func EvaluateCLIResults(cliData api.CLIData, results []api.CLIStepResult) *api.S
 		if stepIndex >= len(results) {
 			return localFailure(stepIndex, 0, "missing result for step")
 		}
+		if failure := evaluateStepResult(stepIndex, step, results[stepIndex]); failure != nil {
+			return failure
+		}
+	}
 
-		switch {
-		case step.CLICommand != nil:
-			result := results[stepIndex].CLICommandResult
-			if result == nil {
-				return localFailure(stepIndex, 0, "missing CLI command result")
-			}
-			if failure := evaluateCLICommandTests(stepIndex, *step.CLICommand, *result); failure != nil {
-				return failure
-			}
-		case step.HTTPRequest != nil:
-			result := results[stepIndex].HTTPRequestResult
-			if result == nil {
-				return localFailure(stepIndex, 0, "missing HTTP request result")
-			}
-			if failure := evaluateHTTPRequestTests(stepIndex, *step.HTTPRequest, *result); failure != nil {
-				return failure
-			}
-		default:
-			return localFailure(stepIndex, 0, "missing step definition")
+	return nil
+}
+
+func evaluateStepResult(stepIndex int, step api.CLIStep, stepResult api.CLIStepResult) *api.StructuredErrCLI {
+	if stepResult.Skipped {
+		return nil
+	}
+	if isLoopStep(step) {
+		return evaluateLoopResult(stepIndex, step, stepResult)
+	}
+
+	switch {
+	case step.CLICommand != nil:
+		result := stepResult.CLICommandResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing CLI command result")
+		}
+		return evaluateCLICommandTests(stepIndex, *step.CLICommand, *result)
+	case step.HTTPRequest != nil:
+		result := stepResult.HTTPRequestResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing HTTP request result")
+		}
+		return evaluateHTTPRequestTests(stepIndex, *step.HTTPRequest, *result)
+	case step.HTTPLoad != nil:
+		result := stepResult.HTTPLoadResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing HTTP load result")
+		}
+		return evaluateHTTPLoadTests(stepIndex, *step.HTTPLoad, *result)
+	case step.Session != nil:
+		result := stepResult.SessionResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing session result")
+		}
+		return evaluateSessionResult(stepIndex, *step.Session, *result)
+	case step.Filesystem != nil:
+		result := stepResult.FilesystemResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing filesystem result")
+		}
+		return evaluateFilesystemTests(stepIndex, *step.Filesystem, *result)
+	case step.Parallel != nil:
+		result := stepResult.ParallelResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing parallel result")
+		}
+		return evaluateParallelResult(stepIndex, *step.Parallel, *result)
+	case step.BackgroundProcess != nil:
+		result := stepResult.BackgroundProcessResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing background process result")
+		}
+		if result.Err != "" {
+			return localFailure(stepIndex, 0, result.Err)
+		}
+		if result.ExitedEarly {
+			return localFailure(stepIndex, 0, fmt.Sprintf("background process exited early with code %d", result.ExitCode))
+		}
+	case step.WaitFor != nil:
+		result := stepResult.WaitForResult
+		if result == nil {
+			return localFailure(stepIndex, 0, "missing wait result")
+		}
+		if result.Err != "" {
+			return localFailure(stepIndex, 0, result.Err)
+		}
+		if !result.Ready {
+			return localFailure(stepIndex, 0, "wait did not succeed")
 		}
+	default:
+		return localFailure(stepIndex, 0, "missing step definition")
 	}
 
 	return nil
@@ -65,26 +124,40 @@ func evaluateCLICommandTests(stepIndex int, cmd api.CLIStepCLICommand, result ap
 		return localFailure(stepIndex, 0, result.Err)
 	}
 
+	expectedText := func(value string) string {
+		return normalizeNeedle(InterpolateVariables(value, result.Variables), cmd.Normalize)
+	}
+	caseInsensitive := isCaseInsensitive(cmd.Normalize)
+	stdout, stderr := foldCase(result.Stdout, caseInsensitive), foldCase(result.Stderr, caseInsensitive)
+
 	for testIndex, test := range cmd.Tests {
 		var err error
 
 		switch {
 		case test.ExitCode != nil:
-			if result.ExitCode != *test.ExitCode {
+			if result.Outcome == api.CLICommandInternalFailure {
+				err = fmt.Errorf("expected exit code %d, but the command failed to run", *test.ExitCode)
+			} else if result.ExitCode != *test.ExitCode {
 				err = fmt.Errorf("expected exit code %d, got %d", *test.ExitCode, result.ExitCode)
 			}
+		case test.ExitCodeNonZero != nil:
+			err = evaluateExitCodeNonZero(*test.ExitCodeNonZero, result)
+		case test.ExitCodeOneOf != nil:
+			err = evaluateExitCodeOneOf(test.ExitCodeOneOf, result)
+		case test.ExitSignal != nil:
+			err = evaluateExitSignal(*test.ExitSignal, result)
 		case len(test.StdoutContainsAll) > 0:
 			for _, contains := range test.StdoutContainsAll {
-				needle := InterpolateVariables(contains, result.Variables)
-				if !strings.Contains(result.Stdout, needle) {
+				needle := expectedText(contains)
+				if !strings.Contains(stdout, foldCase(needle, caseInsensitive)) {
 					err = fmt.Errorf("expected stdout to contain %q", needle)
 					break
 				}
 			}
 		case len(test.StdoutContainsNone) > 0:
 			for _, containsNone := range test.StdoutContainsNone {
-				needle := InterpolateVariables(containsNone, result.Variables)
-				if strings.Contains(result.Stdout, needle) {
+				needle := expectedText(containsNone)
+				if strings.Contains(stdout, foldCase(needle, caseInsensitive)) {
 					err = fmt.Errorf("expected stdout to not contain %q", needle)
 					break
 				}
@@ -95,7 +168,45 @@ func evaluateCLICommandTests(stepIndex int, cmd api.CLIStepCLICommand, result ap
 				err = fmt.Errorf("expected stdout to have more than %d lines, got %d", *test.StdoutLinesGT, lineCount)
 			}
 		case test.StdoutJq != nil:
-			err = evaluateStdoutJq(result.Stdout, *test.StdoutJq, result.Variables)
+			err = evaluateStdoutJq(result.Stdout, *test.StdoutJq, result.Variables, caseInsensitive)
+		case len(test.StderrContainsAll) > 0:
+			for _, contains := range test.StderrContainsAll {
+				needle := expectedText(contains)
+				if !strings.Contains(stderr, foldCase(needle, caseInsensitive)) {
+					err = fmt.Errorf("expected stderr to contain %q", needle)
+					break
+				}
+			}
+		case len(test.StderrContainsNone) > 0:
+			for _, containsNone := range test.StderrContainsNone {
+				needle := expectedText(containsNone)
+				if strings.Contains(stderr, foldCase(needle, caseInsensitive)) {
+					err = fmt.Errorf("expected stderr to not contain %q", needle)
+					break
+				}
+			}
+		case test.StderrJq != nil:
+			err = evaluateStdoutJq(result.Stderr, *test.StderrJq, result.Variables, caseInsensitive)
+		case test.StderrEmpty != nil:
+			if *test.StderrEmpty && result.Stderr != "" {
+				err = fmt.Errorf("expected stderr to be empty")
+			} else if !*test.StderrEmpty && result.Stderr == "" {
+				err = fmt.Errorf("expected stderr to not be empty")
+			}
+		case test.MaxDurationMs != nil:
+			if result.DurationMs > *test.MaxDurationMs {
+				err = fmt.Errorf("expected command to finish within %dms, took %dms", *test.MaxDurationMs, result.DurationMs)
+			}
+		case test.MaxRSSBytes != nil:
+			if result.MaxRSSBytes == 0 {
+				err = fmt.Errorf("max RSS isn't available on this platform")
+			} else if result.MaxRSSBytes > *test.MaxRSSBytes {
+				err = fmt.Errorf("expected max RSS of at most %s, got %s", FormatBytes(*test.MaxRSSBytes), FormatBytes(result.MaxRSSBytes))
+			}
+		case test.StdoutLines != nil:
+			_, err = evaluateStdoutLines(result.Stdout, *test.StdoutLines, result.Variables, caseInsensitive)
+		case test.StdoutSnapshot != nil:
+			err = evaluateStdoutSnapshot(result.Stdout, *test.StdoutSnapshot, caseInsensitive)
 		default:
 			err = fmt.Errorf("unsupported CLI command test")
 		}
@@ -108,6 +219,167 @@ func evaluateCLICommandTests(stepIndex int, cmd api.CLIStepCLICommand, result ap
 	return nil
 }
 
+// evaluateExitCodeNonZero only accepts a command that actually exited; being
+// killed or never running isn't what an "exits with an error" lesson teaches.
+func evaluateExitCodeNonZero(nonZero bool, result api.CLICommandResult) error {
+	if result.Outcome != api.CLICommandExited {
+		return fmt.Errorf("expected the command to exit on its own, but it %s", describeCommandOutcome(result))
+	}
+	if nonZero && result.ExitCode == 0 {
+		return fmt.Errorf("expected a non-zero exit code, got 0")
+	}
+	if !nonZero && result.ExitCode != 0 {
+		return fmt.Errorf("expected exit code 0, got %d", result.ExitCode)
+	}
+	return nil
+}
+
+func evaluateExitCodeOneOf(exitCodes []int, result api.CLICommandResult) error {
+	if result.Outcome != api.CLICommandExited {
+		return fmt.Errorf("expected exit code to be one of %v, but the command %s", exitCodes, describeCommandOutcome(result))
+	}
+	if !slices.Contains(exitCodes, result.ExitCode) {
+		return fmt.Errorf("expected exit code to be one of %v, got %d", exitCodes, result.ExitCode)
+	}
+	return nil
+}
+
+func evaluateExitSignal(signal string, result api.CLICommandResult) error {
+	want := normalizeSignalName(signal)
+	if result.Outcome != api.CLICommandSignaled {
+		return fmt.Errorf("expected the command to be killed by signal %s, but it %s", want, describeCommandOutcome(result))
+	}
+	if result.Signal != want {
+		return fmt.Errorf("expected the command to be killed by signal %s, got %s", want, result.Signal)
+	}
+	return nil
+}
+
+func describeCommandOutcome(result api.CLICommandResult) string {
+	switch result.Outcome {
+	case api.CLICommandExited:
+		return fmt.Sprintf("exited with code %d", result.ExitCode)
+	case api.CLICommandSignaled:
+		return fmt.Sprintf("was killed by signal %s", result.Signal)
+	case api.CLICommandTimedOut:
+		return "timed out"
+	default:
+		return "failed to run"
+	}
+}
+
+// evaluateSessionResult reports a failed action at its own index, and the
+// session's tests after all of the actions.
+func evaluateSessionResult(stepIndex int, session api.CLIStepSession, result api.SessionResult) *api.StructuredErrCLI {
+	if result.ActionsCompleted < len(session.Actions) {
+		message := result.Err
+		if message == "" {
+			message = "session action did not complete"
+		}
+		return localFailure(stepIndex, result.ActionsCompleted, message)
+	}
+	if result.Err != "" {
+		return localFailure(stepIndex, len(session.Actions), result.Err)
+	}
+
+	failure := evaluateCLICommandTests(
+		stepIndex,
+		api.CLIStepCLICommand{Tests: session.Tests},
+		api.CLICommandResult{
+			ExitCode:  result.ExitCode,
+			Outcome:   result.Outcome,
+			Signal:    result.Signal,
+			Stdout:    result.Stdout,
+			Variables: result.Variables,
+		},
+	)
+	if failure != nil {
+		failure.FailedTestIndex += len(session.Actions)
+	}
+	return failure
+}
+
+func evaluateFilesystemTests(stepIndex int, filesystem api.CLIStepFilesystem, result api.FilesystemResult) *api.StructuredErrCLI {
+	for testIndex, test := range filesystem.Tests {
+		if testIndex >= len(result.Entries) {
+			return localFailure(stepIndex, testIndex, "missing filesystem entry for test")
+		}
+		if err := evaluateFilesystemTest(test, result.Entries[testIndex], result.Variables); err != nil {
+			return localFailure(stepIndex, testIndex, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func evaluateFilesystemTest(test api.FilesystemTest, entry api.FilesystemEntry, variables map[string]string) error {
+	if entry.Err != "" {
+		return fmt.Errorf("failed to inspect %s: %s", entry.Path, entry.Err)
+	}
+
+	if test.Exists != nil {
+		if *test.Exists && !entry.Exists {
+			return fmt.Errorf("expected %s to exist", entry.Path)
+		}
+		if !*test.Exists && entry.Exists {
+			return fmt.Errorf("expected %s to not exist", entry.Path)
+		}
+		return nil
+	}
+
+	if !entry.Exists {
+		return fmt.Errorf("expected %s to exist", entry.Path)
+	}
+
+	switch {
+	case test.ContentsContain != nil:
+		needle := InterpolateVariables(*test.ContentsContain, variables)
+		if !strings.Contains(entry.Contents, needle) {
+			return fmt.Errorf("expected %s to contain %q", entry.Path, needle)
+		}
+	case test.ContentsRegex != nil:
+		pattern := InterpolateVariables(*test.ContentsRegex, variables)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid filesystem test configuration")
+		}
+		if !re.MatchString(entry.Contents) {
+			return fmt.Errorf("expected %s to match %q", entry.Path, pattern)
+		}
+	case test.SHA256 != nil:
+		want := strings.ToLower(InterpolateVariables(*test.SHA256, variables))
+		if entry.SHA256 != want {
+			return fmt.Errorf("expected %s to have sha256 %s, got %s", entry.Path, want, entry.SHA256)
+		}
+	case test.Mode != nil:
+		want, err := strconv.ParseUint(*test.Mode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid filesystem test configuration")
+		}
+		if wantMode := formatFileMode(fs.FileMode(want)); entry.Mode != wantMode {
+			return fmt.Errorf("expected %s to have mode %s, got %s", entry.Path, wantMode, entry.Mode)
+		}
+	case test.DirEntries != nil:
+		if !entry.IsDir {
+			return fmt.Errorf("expected %s to be a directory", entry.Path)
+		}
+		want := make([]string, 0, len(test.DirEntries))
+		for _, name := range test.DirEntries {
+			want = append(want, InterpolateVariables(name, variables))
+		}
+		got := slices.Clone(entry.DirEntries)
+		slices.Sort(want)
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("expected %s to contain exactly %v, got %v", entry.Path, want, got)
+		}
+	default:
+		return fmt.Errorf("unsupported filesystem test")
+	}
+
+	return nil
+}
+
 func evaluateHTTPRequestTests(stepIndex int, req api.CLIStepHTTPRequest, result api.HTTPRequestResult) *api.StructuredErrCLI {
 	if result.Err != "" {
 		return localFailure(stepIndex, 0, result.Err)
@@ -231,7 +503,7 @@ func httpJSONExpectedValue(test api.HTTPRequestTestJSONValue, variables map[stri
 	}
 }
 
-func evaluateStdoutJq(stdout string, test api.StdoutJqTest, variables map[string]string) error {
+func evaluateStdoutJq(stdout string, test api.StdoutJqTest, variables map[string]string, caseInsensitive bool) error {
 	queryText := InterpolateVariables(test.Query, variables)
 
 	input, err := parseJqInput(stdout, test.InputMode)
@@ -252,7 +524,7 @@ func evaluateStdoutJq(stdout string, test api.StdoutJqTest, variables map[string
 		if err != nil {
 			return err
 		}
-		if !compareValues(results[i], api.OperatorType(expected.Operator), want) {
+		if !compareValues(foldJqCase(results[i], caseInsensitive), api.OperatorType(expected.Operator), foldJqCase(want, caseInsensitive)) {
 			return fmt.Errorf("expected jq result %d to be %s %v, got %v", i+1, expected.Operator, want, results[i])
 		}
 	}
@@ -100,12 +100,86 @@ func TestEvaluateStdoutJq(t *testing.T) {
 			},
 		},
 		map[string]string{},
+		false,
 	)
 	if err != nil {
 		t.Fatalf("unexpected jq failure: %v", err)
 	}
 }
 
+func TestEvaluateCLICommandStderrTests(t *testing.T) {
+	tests := []struct {
+		name    string
+		test    api.CLICommandTest
+		stderr  string
+		wantErr string
+	}{
+		{
+			name:   "contains all",
+			test:   api.CLICommandTest{StderrContainsAll: []string{"error:", "${file}"}},
+			stderr: "error: config.json not found",
+		},
+		{
+			name:    "contains all missing needle",
+			test:    api.CLICommandTest{StderrContainsAll: []string{"warning:"}},
+			stderr:  "error: config.json not found",
+			wantErr: `expected stderr to contain "warning:"`,
+		},
+		{
+			name:    "contains none",
+			test:    api.CLICommandTest{StderrContainsNone: []string{"panic"}},
+			stderr:  "panic: runtime error",
+			wantErr: `expected stderr to not contain "panic"`,
+		},
+		{
+			name: "jq",
+			test: api.CLICommandTest{StderrJq: &api.StdoutJqTest{
+				Query:           ".level",
+				ExpectedResults: []api.JqExpectedResult{{Type: api.JqTypeString, Operator: "==", Value: "error"}},
+			}},
+			stderr: `{"level":"error"}`,
+		},
+		{
+			name: "empty",
+			test: api.CLICommandTest{StderrEmpty: boolPtr(true)},
+		},
+		{
+			name:    "empty with output",
+			test:    api.CLICommandTest{StderrEmpty: boolPtr(true)},
+			stderr:  "oops",
+			wantErr: "expected stderr to be empty",
+		},
+		{
+			name:    "not empty",
+			test:    api.CLICommandTest{StderrEmpty: boolPtr(false)},
+			wantErr: "expected stderr to not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := evaluateCLICommandTests(
+				0,
+				api.CLIStepCLICommand{Tests: []api.CLICommandTest{tt.test}},
+				api.CLICommandResult{
+					Stdout:    "error: should only be checked on stderr",
+					Stderr:    tt.stderr,
+					Variables: map[string]string{"file": "config.json"},
+				},
+			)
+			if tt.wantErr == "" {
+				if failure != nil {
+					t.Fatalf("unexpected failure: %#v", failure)
+				}
+				return
+			}
+			if failure == nil || failure.ErrorMessage != tt.wantErr {
+				t.Fatalf("failure = %#v, want %q", failure, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestLocalSubmissionEventRejectsMissingHTTPResponseCaptures(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -219,3 +293,142 @@ func intPtr(v int) *int {
 func stringPtr(v string) *string {
 	return &v
 }
+
+func TestEvaluateCLICommandExitCodeForms(t *testing.T) {
+	exited := func(code int) api.CLICommandResult {
+		return api.CLICommandResult{Outcome: api.CLICommandExited, ExitCode: code}
+	}
+	signaled := api.CLICommandResult{Outcome: api.CLICommandSignaled, ExitCode: -1, Signal: "SIGTERM"}
+	internal := api.CLICommandResult{Outcome: api.CLICommandInternalFailure, ExitCode: api.ExitCodeInternalFailure}
+
+	tests := []struct {
+		name    string
+		test    api.CLICommandTest
+		result  api.CLICommandResult
+		wantErr string
+	}{
+		{
+			name:   "non-zero",
+			test:   api.CLICommandTest{ExitCodeNonZero: boolPtr(true)},
+			result: exited(2),
+		},
+		{
+			name:    "non-zero got zero",
+			test:    api.CLICommandTest{ExitCodeNonZero: boolPtr(true)},
+			result:  exited(0),
+			wantErr: "expected a non-zero exit code, got 0",
+		},
+		{
+			name:    "non-zero ignores internal failure",
+			test:    api.CLICommandTest{ExitCodeNonZero: boolPtr(true)},
+			result:  internal,
+			wantErr: "expected the command to exit on its own, but it failed to run",
+		},
+		{
+			name:   "one of",
+			test:   api.CLICommandTest{ExitCodeOneOf: []int{1, 2}},
+			result: exited(2),
+		},
+		{
+			name:    "one of mismatch",
+			test:    api.CLICommandTest{ExitCodeOneOf: []int{1, 2}},
+			result:  exited(3),
+			wantErr: "expected exit code to be one of [1 2], got 3",
+		},
+		{
+			name:   "signal",
+			test:   api.CLICommandTest{ExitSignal: stringPtr("term")},
+			result: signaled,
+		},
+		{
+			name:    "signal but exited",
+			test:    api.CLICommandTest{ExitSignal: stringPtr("SIGKILL")},
+			result:  exited(0),
+			wantErr: "expected the command to be killed by signal SIGKILL, but it exited with code 0",
+		},
+		{
+			name:    "exact code with internal failure",
+			test:    api.CLICommandTest{ExitCode: intPtr(-2)},
+			result:  internal,
+			wantErr: "expected exit code -2, but the command failed to run",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := evaluateCLICommandTests(0, api.CLIStepCLICommand{Tests: []api.CLICommandTest{tt.test}}, tt.result)
+			if tt.wantErr == "" {
+				if failure != nil {
+					t.Fatalf("unexpected failure: %#v", failure)
+				}
+				return
+			}
+			if failure == nil || failure.ErrorMessage != tt.wantErr {
+				t.Fatalf("failure = %#v, want %q", failure, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluateStdoutLines(t *testing.T) {
+	stdout := "name: boots\nlevel: 3\nstatus: ok\ndone"
+
+	tests := []struct {
+		name     string
+		test     api.StdoutLinesTest
+		wantLine int
+		wantErr  string
+	}{
+		{name: "line equals", test: api.StdoutLinesTest{Line: intPtr(1), Equals: stringPtr("name: ${name}")}},
+		{name: "last line", test: api.StdoutLinesTest{Line: intPtr(-1), Equals: stringPtr("done")}},
+		{
+			name:     "line matches",
+			test:     api.StdoutLinesTest{Line: intPtr(2), Matches: stringPtr(`^level: [a-z]+$`)},
+			wantLine: 2,
+			wantErr:  `expected line 2 to match "^level: [a-z]+$", got "level: 3"`,
+		},
+		{
+			name:    "line out of range",
+			test:    api.StdoutLinesTest{Line: intPtr(9), Equals: stringPtr("x")},
+			wantErr: "expected stdout to have a line 9, got 4 lines",
+		},
+		{name: "count", test: api.StdoutLinesTest{Count: intPtr(4)}},
+		{
+			name:     "count too many",
+			test:     api.StdoutLinesTest{Count: intPtr(3)},
+			wantLine: 4,
+			wantErr:  "expected stdout to have exactly 3 lines, got 4",
+		},
+		{
+			name:     "count less than",
+			test:     api.StdoutLinesTest{CountLT: intPtr(3)},
+			wantLine: 3,
+			wantErr:  "expected stdout to have fewer than 3 lines, got 4",
+		},
+		{name: "in order", test: api.StdoutLinesTest{InOrder: []string{"name", "status", "done"}}},
+		{
+			name:     "out of order",
+			test:     api.StdoutLinesTest{InOrder: []string{"status", "level"}},
+			wantLine: 2,
+			wantErr:  `expected a line containing "level" after line 3, found it on line 2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := evaluateStdoutLines(stdout, tt.test, map[string]string{"name": "boots"}, false)
+			if line != tt.wantLine {
+				t.Errorf("line = %d, want %d", line, tt.wantLine)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
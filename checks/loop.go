@@ -0,0 +1,213 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/bootdotdev/bootdev/messages"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/goccy/go-json"
+)
+
+const (
+	maxLoopIterations = 100
+
+	loopIndexVariable = "index"
+	loopItemVariable  = "item"
+)
+
+func isLoopStep(step api.CLIStep) bool {
+	return step.Repeat > 0 || step.Foreach != ""
+}
+
+// runLoopStep runs a repeat or foreach step's CLI command or HTTP request once
+// per iteration. Each iteration shows up as one of the step's tests, and the
+// variables it sets carry over to the next one.
+func runLoopStep(
+	stepIndex int,
+	step api.CLIStep,
+	client *http.Client,
+	baseURL string,
+	variables map[string]string,
+	send func(tea.Msg),
+) (api.CLIStepResult, error) {
+	if step.Repeat > 0 && step.Foreach != "" {
+		return api.CLIStepResult{}, errors.New("unable to run lesson: a step can't have both repeat and foreach")
+	}
+	if step.Repeat > maxLoopIterations {
+		return api.CLIStepResult{}, fmt.Errorf("unable to run lesson: repeat can be at most %d", maxLoopIterations)
+	}
+
+	inner := step
+	inner.Repeat, inner.Foreach = 0, ""
+	start := messages.StartStepMsg{
+		Description:     step.Description,
+		Loop:            describeLoop(step),
+		NoPenaltyOnFail: step.NoPenaltyOnFail,
+	}
+	var run func() api.CLIStepResult
+	var describe func() string
+	var sleepAfterMs *int
+	switch {
+	case step.CLICommand != nil:
+		command, err := resolveCLICommand(*step.CLICommand)
+		if err != nil {
+			return api.CLIStepResult{}, err
+		}
+		start.CMD, start.TmdlQuery = command.Command, command.StdoutFilterTmdl
+		run = func() api.CLIStepResult {
+			result := runCLICommand(command, variables)
+			result.JqOutputs = collectJqOutputs(command, result)
+			return api.CLIStepResult{CLICommandResult: &result}
+		}
+		describe = func() string {
+			return InterpolateVariables(command.Command, variables)
+		}
+		sleepAfterMs = command.SleepAfterMs
+	case step.HTTPRequest != nil:
+		fullURL := strings.Replace(step.HTTPRequest.Request.FullURL, api.BaseURLPlaceholder, baseURL, 1)
+		start.URL, start.Method = InterpolateVariables(fullURL, variables), step.HTTPRequest.Request.Method
+		run = func() api.CLIStepResult {
+			result := runHTTPRequest(client, baseURL, variables, *step.HTTPRequest)
+			return api.CLIStepResult{HTTPRequestResult: &result}
+		}
+		describe = func() string {
+			return fmt.Sprintf("%s %s", step.HTTPRequest.Request.Method, InterpolateVariables(fullURL, variables))
+		}
+		sleepAfterMs = step.HTTPRequest.SleepAfterMs
+	default:
+		return api.CLIStepResult{}, errors.New("unable to run lesson: only CLI command and HTTP request steps can repeat")
+	}
+	send(start)
+
+	items, err := loopItems(step, variables)
+	if err != nil {
+		result := api.CLIStepResult{IterationsErr: err.Error()}
+		send(messages.ResolveStepMsg{Index: stepIndex, Result: &result})
+		return result, nil
+	}
+
+	iterations := make([]api.CLIStepResult, len(items))
+	for k, item := range items {
+		setLoopVariables(variables, k, item, step.Foreach != "")
+		send(messages.StartTestMsg{Text: fmt.Sprintf("Iteration %d/%d: %s", k+1, len(items), describe())})
+		iterations[k] = runWithRetry(stepIndex, inner, variables, send, run)
+		handleSleep(sleepAfterMs, send)
+	}
+	clearLoopVariables(variables)
+
+	for k := range iterations {
+		send(messages.ResolveTestMsg{
+			StepIndex: stepIndex,
+			TestIndex: k,
+		})
+	}
+	result := api.CLIStepResult{Iterations: iterations}
+	send(messages.ResolveStepMsg{Index: stepIndex, Result: &result})
+	return result, nil
+}
+
+// loopItems returns one entry per iteration. Repeat steps have no items, so
+// their entries are all nil.
+func loopItems(step api.CLIStep, variables map[string]string) ([]any, error) {
+	if step.Foreach == "" {
+		return make([]any, step.Repeat), nil
+	}
+
+	value := InterpolateVariables(step.Foreach, variables)
+	if names := InterpolationNames(value); len(names) > 0 {
+		return nil, fmt.Errorf("foreach variable %s isn't set", names[0])
+	}
+	var items []any
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		return nil, fmt.Errorf("foreach value isn't a JSON array: %s", truncateAndStringifyBody([]byte(value)))
+	}
+	if len(items) > maxLoopIterations {
+		return nil, fmt.Errorf("foreach has %d items, but at most %d are allowed", len(items), maxLoopIterations)
+	}
+	return items, nil
+}
+
+// setLoopVariables sets ${index} from 0, and for foreach steps ${item}. Object
+// items also get ${item.<key>} for each of their keys.
+func setLoopVariables(variables map[string]string, index int, item any, hasItem bool) {
+	clearLoopVariables(variables)
+	variables[loopIndexVariable] = strconv.Itoa(index)
+	if !hasItem {
+		return
+	}
+	variables[loopItemVariable] = jsonVariableValue(item)
+	if fields, ok := item.(map[string]any); ok {
+		for key, value := range fields {
+			variables[loopItemVariable+"."+key] = jsonVariableValue(value)
+		}
+	}
+}
+
+func clearLoopVariables(variables map[string]string) {
+	for name := range variables {
+		if isLoopVariable(name) {
+			delete(variables, name)
+		}
+	}
+}
+
+// checkLoopVariableNames rejects lessons with loop steps that capture a variable
+// named like a loop variable, since every iteration would overwrite it.
+func checkLoopVariableNames(cliData api.CLIData) error {
+	if !slices.ContainsFunc(cliData.Steps, isLoopStep) {
+		return nil
+	}
+	for _, step := range cliData.Steps {
+		for _, name := range stepVariableNames(step, false) {
+			if isLoopVariable(name) {
+				return fmt.Errorf("unable to run lesson: variable %q is reserved for repeat and foreach steps", name)
+			}
+		}
+	}
+	return nil
+}
+
+func isLoopVariable(name string) bool {
+	return name == loopIndexVariable || name == loopItemVariable || strings.HasPrefix(name, loopItemVariable+".")
+}
+
+func describeLoop(step api.CLIStep) string {
+	if step.Foreach != "" {
+		return fmt.Sprintf("for each of %s", step.Foreach)
+	}
+	return fmt.Sprintf("%d times", step.Repeat)
+}
+
+func evaluateLoopResult(stepIndex int, step api.CLIStep, stepResult api.CLIStepResult) *api.StructuredErrCLI {
+	if stepResult.IterationsErr != "" {
+		return localFailure(stepIndex, 0, stepResult.IterationsErr)
+	}
+
+	inner := step
+	inner.Repeat, inner.Foreach = 0, ""
+	for k, iteration := range stepResult.Iterations {
+		if failure := evaluateStepResult(stepIndex, inner, iteration); failure != nil {
+			return localFailure(stepIndex, k, fmt.Sprintf("iteration %d: %s", k+1, failure.ErrorMessage))
+		}
+	}
+	return nil
+}
+
+// loopTestCount is how many iterations a loop step showed as tests. Foreach
+// steps only know once they've run, so the count comes from the result, or 0
+// when the step never ran.
+func loopTestCount(step api.CLIStep, results []api.CLIStepResult, stepIndex int) int {
+	if stepIndex < len(results) {
+		return len(results[stepIndex].Iterations)
+	}
+	if step.Foreach != "" {
+		return 0
+	}
+	return step.Repeat
+}
@@ -0,0 +1,186 @@
+package checks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/bootdotdev/bootdev/messages"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCLIChecksRunsForeachOverCapturedArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			_, _ = w.Write([]byte(`{"items":[{"id":"a"},{"id":"b"},{"id":"c"}]}`))
+		case "/items/a", "/items/b":
+			_, _ = w.Write([]byte(`{"index":"` + r.URL.Query().Get("i") + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{HTTPRequest: &api.CLIStepHTTPRequest{
+			Request: api.HTTPRequest{Method: http.MethodGet, FullURL: api.BaseURLPlaceholder + "/items"},
+			ResponseVariables: []api.HTTPRequestResponseVariable{
+				{Name: "items", Path: ".items"},
+				{Name: "ids", Path: ".items[].id"},
+			},
+		}},
+		{
+			Foreach: "${items}",
+			HTTPRequest: &api.CLIStepHTTPRequest{
+				Request:           api.HTTPRequest{Method: http.MethodGet, FullURL: api.BaseURLPlaceholder + "/items/${item.id}?i=${index}"},
+				Tests:             []api.HTTPRequestTest{{StatusCode: intPtr(http.StatusOK)}},
+				ResponseVariables: []api.HTTPRequestResponseVariable{{Name: "lastIndex", Path: ".index"}},
+			},
+		},
+	}}
+
+	var texts []string
+	results, err := CLIChecks(cliData, server.URL, 0, func(msg tea.Msg) {
+		if msg, ok := msg.(messages.StartTestMsg); ok {
+			texts = append(texts, msg.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	if got := results[0].HTTPRequestResult.Variables["ids"]; got != `["a","b","c"]` {
+		t.Fatalf("ids = %q, want a JSON array", got)
+	}
+	wantTexts := []string{
+		"Iteration 1/3: GET " + server.URL + "/items/a?i=0",
+		"Iteration 2/3: GET " + server.URL + "/items/b?i=1",
+		"Iteration 3/3: GET " + server.URL + "/items/c?i=2",
+	}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Fatalf("texts = %q, want %q", texts, wantTexts)
+	}
+
+	iterations := results[1].Iterations
+	if len(iterations) != 3 || iterations[1].HTTPRequestResult.Variables["lastIndex"] != "1" {
+		t.Fatalf("iterations = %#v, want three with variables carried over", iterations)
+	}
+	failure := EvaluateCLIResults(cliData, results)
+	if failure == nil || failure.FailedStepIndex != 1 || failure.FailedTestIndex != 2 ||
+		failure.ErrorMessage != "iteration 3: expected status code 200, got 404" {
+		t.Fatalf("failure = %#v, want the third iteration to fail", failure)
+	}
+}
+
+func TestCLIChecksRepeatsCommandWithIndex(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{
+			Repeat: 3,
+			CLICommand: &api.CLIStepCLICommand{
+				Command:         "echo page=${index}",
+				Tests:           []api.CLICommandTest{{StdoutContainsAll: []string{"page=${index}"}}},
+				StdoutVariables: []api.CLICommandStdoutVariable{{Name: "page", Regex: `page=(\d+)`}},
+			},
+		},
+		{CLICommand: &api.CLIStepCLICommand{Command: "echo ${page}-${index}"}},
+	}}
+
+	results, err := CLIChecks(cliData, "", 0, func(tea.Msg) {})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	if len(results[0].Iterations) != 3 {
+		t.Fatalf("iterations = %d, want 3", len(results[0].Iterations))
+	}
+	if failure := EvaluateCLIResults(cliData, results); failure != nil {
+		t.Fatalf("EvaluateCLIResults() = %#v, want every iteration to pass", failure)
+	}
+	if got := results[1].CLICommandResult.FinalCommand; got != "echo 2-${index}" {
+		t.Fatalf("final command = %q, want the loop variable gone after the loop", got)
+	}
+}
+
+func TestLoopStepErrors(t *testing.T) {
+	result, err := runLoopStep(0, api.CLIStep{Foreach: "${missing}", CLICommand: &api.CLIStepCLICommand{}}, nil, "", map[string]string{}, func(tea.Msg) {})
+	if err != nil || result.IterationsErr != "foreach variable missing isn't set" {
+		t.Fatalf("result = %#v, err = %v, want an unset variable error", result, err)
+	}
+
+	result, _ = runLoopStep(0, api.CLIStep{Foreach: "${items}", CLICommand: &api.CLIStepCLICommand{}}, nil, "", map[string]string{"items": "nope"}, func(tea.Msg) {})
+	if !strings.HasPrefix(result.IterationsErr, "foreach value isn't a JSON array") {
+		t.Fatalf("IterationsErr = %q, want a JSON array error", result.IterationsErr)
+	}
+
+	_, err = runLoopStep(0, api.CLIStep{Repeat: 2, WaitFor: &api.CLIStepWaitFor{}}, nil, "", nil, func(tea.Msg) {})
+	if err == nil || !strings.Contains(err.Error(), "only CLI command and HTTP request steps can repeat") {
+		t.Fatalf("err = %v, want an unsupported step error", err)
+	}
+}
+
+func TestJqVariableValueCapturesArrays(t *testing.T) {
+	tests := []struct {
+		vals []any
+		want string
+		ok   bool
+	}{
+		{vals: nil, ok: false},
+		{vals: []any{nil}, ok: false},
+		{vals: []any{"a"}, want: "a", ok: true},
+		{vals: []any{1.5}, want: "1.5", ok: true},
+		{vals: []any{"a", 2}, want: `["a",2]`, ok: true},
+		{vals: []any{map[string]any{"id": "a"}}, want: `{"id":"a"}`, ok: true},
+	}
+	for _, tt := range tests {
+		got, ok := jqVariableValue(tt.vals)
+		if got != tt.want || ok != tt.ok {
+			t.Fatalf("jqVariableValue(%v) = %q, %v, want %q, %v", tt.vals, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCLIChecksRejectsCapturedLoopVariableNames(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{CLICommand: &api.CLIStepCLICommand{
+			Command:         "echo id=1",
+			StdoutVariables: []api.CLICommandStdoutVariable{{Name: "item.id", Regex: `id=(\d+)`}},
+		}},
+		{Repeat: 2, CLICommand: &api.CLIStepCLICommand{Command: "echo ${index}"}},
+	}}
+
+	_, err := CLIChecks(cliData, "", 0, func(tea.Msg) {})
+	if err == nil || !strings.Contains(err.Error(), `variable "item.id" is reserved`) {
+		t.Fatalf("err = %v, want a reserved variable error", err)
+	}
+}
+
+func TestForeachOverSecretMakesItemsSecret(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{CLICommand: &api.CLIStepCLICommand{
+			Command:         `echo '[{"key":"k1"},{"key":"k2"}]'`,
+			StdoutVariables: []api.CLICommandStdoutVariable{{Name: "keys", Regex: `(.+)`, Secret: true}},
+		}},
+		{Foreach: "${keys}", CLICommand: &api.CLIStepCLICommand{Command: "echo ${item.key}"}},
+	}}
+
+	results, err := CLIChecks(cliData, "", 0, func(tea.Msg) {})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+	names := SecretVariableNames(cliData)
+	if !slices.Contains(names, "item") {
+		t.Fatalf("secret names = %v, want item", names)
+	}
+	values := SecretValues(names, StepResultVariableSets(results[1])...)
+	for _, want := range []string{"k1", "k2"} {
+		if !slices.Contains(values, want) {
+			t.Fatalf("secret values = %q, want %q", values, want)
+		}
+	}
+}
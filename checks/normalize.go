@@ -0,0 +1,79 @@
+package checks
+
+import (
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"golang.org/x/text/unicode/norm"
+)
+
+var carriageReturnReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// normalizeOutput applies the command's normalize options to one output stream.
+// CaseInsensitive is left to the comparisons, so variables, jq paths and the
+// submission still see the output's original case.
+func normalizeOutput(output string, normalize api.CLICommandNormalize) string {
+	if normalize.StripANSI {
+		output = ansiEscapePattern.ReplaceAllString(output, "")
+	}
+	if normalize.NormalizeNewlines {
+		output = carriageReturnReplacer.Replace(output)
+	}
+	if normalize.UnicodeNFC {
+		output = norm.NFC.String(output)
+	}
+	if normalize.TrimLines {
+		lines := strings.Split(output, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace(line)
+		}
+		output = strings.Join(lines, "\n")
+	}
+	return output
+}
+
+// normalizeNeedle puts expected text in the same Unicode form as the output
+// it's compared against.
+func normalizeNeedle(needle string, normalize *api.CLICommandNormalize) string {
+	if normalize != nil && normalize.UnicodeNFC {
+		needle = norm.NFC.String(needle)
+	}
+	return needle
+}
+
+func isCaseInsensitive(normalize *api.CLICommandNormalize) bool {
+	return normalize != nil && normalize.CaseInsensitive
+}
+
+// foldCase lowercases text for a case-insensitive comparison
+func foldCase(text string, caseInsensitive bool) string {
+	if caseInsensitive {
+		return strings.ToLower(text)
+	}
+	return text
+}
+
+// foldJqCase lowercases the strings inside a jq value, but not object keys
+func foldJqCase(value any, caseInsensitive bool) any {
+	if !caseInsensitive {
+		return value
+	}
+	switch value := value.(type) {
+	case string:
+		return strings.ToLower(value)
+	case []any:
+		folded := make([]any, len(value))
+		for i, item := range value {
+			folded[i] = foldJqCase(item, true)
+		}
+		return folded
+	case map[string]any:
+		folded := make(map[string]any, len(value))
+		for key, item := range value {
+			folded[key] = foldJqCase(item, true)
+		}
+		return folded
+	default:
+		return value
+	}
+}
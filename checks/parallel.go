@@ -0,0 +1,219 @@
+package checks
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+// maxParallelCopies bounds how many processes and requests a parallel step
+// starts at once, counting every step's Count
+const maxParallelCopies = 100
+
+// parallelCopies expands each step's Count into that many entries, which is
+// the order results and test indexes use.
+func parallelCopies(parallel api.CLIStepParallel) []api.ParallelStep {
+	var copies []api.ParallelStep
+	for _, step := range parallel.Steps {
+		for range max(step.Count, 1) {
+			copies = append(copies, step)
+		}
+	}
+	return copies
+}
+
+func checkParallelCopies(parallel api.CLIStepParallel) error {
+	total := 0
+	for _, step := range parallel.Steps {
+		total += max(step.Count, 1)
+	}
+	if total > maxParallelCopies {
+		return fmt.Errorf("unable to run lesson: a parallel step can run at most %d copies", maxParallelCopies)
+	}
+	return nil
+}
+
+// runParallel starts every copy behind a barrier so none of them gets a head
+// start. Each copy works on its own clone of the variables, and the ones they
+// set are merged back in step order once they've all finished.
+func runParallel(client *http.Client, baseURL string, parallel api.CLIStepParallel, variables map[string]string) api.ParallelResult {
+	copies := parallelCopies(parallel)
+	results := make([]api.CLIStepResult, len(copies))
+	copyVariables := make([]map[string]string, len(copies))
+
+	var ready, done sync.WaitGroup
+	start := make(chan struct{})
+	for i, step := range copies {
+		copyVariables[i] = maps.Clone(variables)
+		ready.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			ready.Done()
+			<-start
+			results[i] = runParallelStep(client, baseURL, step, copyVariables[i])
+		}()
+	}
+	ready.Wait()
+	close(start)
+	done.Wait()
+
+	for _, vars := range copyVariables {
+		maps.Copy(variables, vars)
+	}
+	return api.ParallelResult{Results: results}
+}
+
+func runParallelStep(client *http.Client, baseURL string, step api.ParallelStep, variables map[string]string) api.CLIStepResult {
+	switch {
+	case step.CLICommand != nil:
+		command, err := resolveCLICommand(*step.CLICommand)
+		if err != nil {
+			return api.CLIStepResult{CLICommandResult: &api.CLICommandResult{
+				Err:      err.Error(),
+				Outcome:  api.CLICommandInternalFailure,
+				ExitCode: api.ExitCodeInternalFailure,
+				Command:  command,
+			}}
+		}
+		result := runCLICommand(command, variables)
+		result.JqOutputs = collectJqOutputs(command, result)
+		return api.CLIStepResult{CLICommandResult: &result}
+	case step.HTTPRequest != nil:
+		result := runHTTPRequest(client, baseURL, variables, *step.HTTPRequest)
+		return api.CLIStepResult{HTTPRequestResult: &result}
+	default:
+		return api.CLIStepResult{}
+	}
+}
+
+func evaluateParallelResult(stepIndex int, parallel api.CLIStepParallel, result api.ParallelResult) *api.StructuredErrCLI {
+	copies := parallelCopies(parallel)
+	if len(result.Results) != len(copies) {
+		return localFailure(stepIndex, 0, "missing parallel step results")
+	}
+
+	for i, step := range copies {
+		var failure *api.StructuredErrCLI
+		copyResult := result.Results[i]
+		switch {
+		case step.CLICommand != nil && copyResult.CLICommandResult != nil:
+			failure = evaluateCLICommandTests(stepIndex, copyResult.CLICommandResult.Command, *copyResult.CLICommandResult)
+		case step.HTTPRequest != nil && copyResult.HTTPRequestResult != nil:
+			failure = evaluateHTTPRequestTests(stepIndex, *step.HTTPRequest, *copyResult.HTTPRequestResult)
+		default:
+			failure = localFailure(stepIndex, i, "missing parallel step definition")
+		}
+		if failure != nil {
+			return localFailure(stepIndex, i, fmt.Sprintf("parallel step %d: %s", i+1, failure.ErrorMessage))
+		}
+	}
+
+	for j, test := range parallel.Tests {
+		if err := evaluateParallelTest(test, result.Results); err != nil {
+			return localFailure(stepIndex, len(copies)+j, err.Error())
+		}
+	}
+	return nil
+}
+
+func evaluateParallelTest(test api.ParallelTest, results []api.CLIStepResult) error {
+	matched := 0
+	for _, result := range results {
+		if parallelResultMatches(test, result) {
+			matched++
+		}
+	}
+
+	if countInRange(matched, len(results), test.Exactly, test.AtLeast, test.AtMost) {
+		return nil
+	}
+	return fmt.Errorf(
+		"expected %s of %d results to %s, got %d",
+		describeCount(test.Exactly, test.AtLeast, test.AtMost), len(results), describeParallelMatch(test), matched,
+	)
+}
+
+// countInRange checks count against whichever bounds are set, and against total
+// when none are.
+func countInRange(count, total int, exactly, atLeast, atMost *int) bool {
+	if exactly == nil && atLeast == nil && atMost == nil {
+		return count == total
+	}
+	if exactly != nil && count != *exactly {
+		return false
+	}
+	if atLeast != nil && count < *atLeast {
+		return false
+	}
+	return atMost == nil || count <= *atMost
+}
+
+func parallelResultMatches(test api.ParallelTest, result api.CLIStepResult) bool {
+	if test.StatusCode != nil {
+		if result.HTTPRequestResult == nil || result.HTTPRequestResult.StatusCode != *test.StatusCode {
+			return false
+		}
+	}
+	if test.ExitCode != nil {
+		if result.CLICommandResult == nil || result.CLICommandResult.ExitCode != *test.ExitCode {
+			return false
+		}
+	}
+	return true
+}
+
+func describeCount(exactly, atLeast, atMost *int) string {
+	if exactly != nil {
+		return fmt.Sprintf("exactly %d", *exactly)
+	}
+	var parts []string
+	if atLeast != nil {
+		parts = append(parts, fmt.Sprintf("at least %d", *atLeast))
+	}
+	if atMost != nil {
+		parts = append(parts, fmt.Sprintf("at most %d", *atMost))
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, " and ")
+}
+
+func describeParallelMatch(test api.ParallelTest) string {
+	var parts []string
+	if test.StatusCode != nil {
+		parts = append(parts, fmt.Sprintf("return status %d", *test.StatusCode))
+	}
+	if test.ExitCode != nil {
+		parts = append(parts, fmt.Sprintf("exit with code %d", *test.ExitCode))
+	}
+	if len(parts) == 0 {
+		return "finish"
+	}
+	return strings.Join(parts, " and ")
+}
+
+func prettyPrintParallelTest(test api.ParallelTest) string {
+	return fmt.Sprintf("Expect %s of the results to %s", describeCount(test.Exactly, test.AtLeast, test.AtMost), describeParallelMatch(test))
+}
+
+// prettyPrintParallelStep labels a copy with its position so the concurrent
+// steps can be told apart in the output.
+func prettyPrintParallelStep(index, total int, step api.ParallelStep, baseURL string, variables map[string]string) string {
+	label := fmt.Sprintf("[%d/%d]", index+1, total)
+	switch {
+	case step.CLICommand != nil:
+		return fmt.Sprintf("%s Command: %s", label, InterpolateVariables(step.CLICommand.CommandFor(runtime.GOOS), variables))
+	case step.HTTPRequest != nil:
+		fullURL := strings.Replace(step.HTTPRequest.Request.FullURL, api.BaseURLPlaceholder, baseURL, 1)
+		return fmt.Sprintf("%s %s %s", label, step.HTTPRequest.Request.Method, InterpolateVariables(fullURL, variables))
+	default:
+		return label
+	}
+}
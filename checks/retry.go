@@ -0,0 +1,50 @@
+package checks
+
+import (
+	"maps"
+	"time"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/bootdotdev/bootdev/messages"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runWithRetry calls run until the step's tests pass locally or the step's retry
+// attempts are used up, and returns the final attempt. Variables captured by a
+// failed attempt are discarded before the next one.
+func runWithRetry(
+	stepIndex int,
+	step api.CLIStep,
+	variables map[string]string,
+	send func(tea.Msg),
+	run func() api.CLIStepResult,
+) api.CLIStepResult {
+	if step.Retry == nil || step.Retry.Attempts <= 1 {
+		return run()
+	}
+
+	initialVariables := maps.Clone(variables)
+	delay := durationFromMs(step.Retry.DelayMs, 0)
+	for attempt := 1; ; attempt++ {
+		result := run()
+		failure := evaluateStepResult(stepIndex, step, result)
+		if failure == nil || attempt >= step.Retry.Attempts {
+			return result
+		}
+
+		send(messages.RetryStepMsg{
+			Index:       stepIndex,
+			Attempt:     attempt,
+			MaxAttempts: step.Retry.Attempts,
+			Error:       failure.ErrorMessage,
+			DelayMs:     int(delay.Milliseconds()),
+		})
+		time.Sleep(delay)
+		if step.Retry.ExponentialBackoff {
+			delay *= 2
+		}
+
+		clear(variables)
+		maps.Copy(variables, initialVariables)
+	}
+}
@@ -2,6 +2,7 @@ package checks
 
 import (
 	"errors"
+	"maps"
 	"net/http"
 	"strings"
 	"time"
@@ -13,10 +14,14 @@ import (
 
 const lessonHTTPRequestTimeout = 30 * time.Second
 
-func CLIChecks(cliData api.CLIData, overrideBaseURL string, send func(tea.Msg)) ([]api.CLIStepResult, error) {
+func CLIChecks(cliData api.CLIData, overrideBaseURL string, seed int64, send func(tea.Msg)) ([]api.CLIStepResult, error) {
 	if cliData.BaseURLDefault == api.BaseURLOverrideRequired && overrideBaseURL == "" {
 		return nil, errors.New("lesson requires a base URL override: `bootdev configure base_url <url>`")
 	}
+	if err := checkLoopVariableNames(cliData); err != nil {
+		return nil, err
+	}
+	applyCommandTimeoutDefault(cliData)
 
 	client := &http.Client{Timeout: lessonHTTPRequestTimeout}
 	results := make([]api.CLIStepResult, len(cliData.Steps))
@@ -26,27 +31,59 @@ func CLIChecks(cliData api.CLIData, overrideBaseURL string, send func(tea.Msg))
 		baseURL = cliData.BaseURLDefault
 	}
 	baseURL = strings.TrimSuffix(baseURL, "/")
-	variables := make(map[string]string)
+	variables := builtinVariables(cliData, seed, time.Now())
 	if baseURL != "" {
 		variables["baseURL"] = baseURL
 	}
 
+	if secretNames := SecretVariableNames(cliData); len(secretNames) > 0 {
+		send(messages.SecretVariablesMsg{Names: secretNames})
+	}
+
+	var backgroundProcesses []*backgroundProcess
+	defer func() {
+		for _, process := range backgroundProcesses {
+			process.stop()
+		}
+	}()
+
 	for i, step := range cliData.Steps {
+		if run, reason := shouldRunStep(i, cliData, results, variables); !run {
+			send(skippedStepMsg(step, baseURL, variables))
+			results[i] = api.CLIStepResult{Skipped: true, SkipReason: reason}
+			send(messages.SkipStepMsg{Index: i, Reason: reason})
+			continue
+		}
+
+		if isLoopStep(step) {
+			result, err := runLoopStep(i, step, client, baseURL, variables, send)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = result
+			continue
+		}
+
 		switch {
 		case step.CLICommand != nil:
+			command, err := resolveCLICommand(*step.CLICommand)
+			if err != nil {
+				return nil, err
+			}
 			send(messages.StartStepMsg{
 				Description:     step.Description,
-				CMD:             step.CLICommand.Command,
-				TmdlQuery:       step.CLICommand.StdoutFilterTmdl,
+				CMD:             command.Command,
+				TmdlQuery:       command.StdoutFilterTmdl,
 				NoPenaltyOnFail: step.NoPenaltyOnFail,
 			})
 
-			result := runCLICommand(*step.CLICommand, variables)
-			result.JqOutputs = collectStdoutJqOutputs(*step.CLICommand, result)
-			results[i].CLICommandResult = &result
-
-			sendCLICommandResults(send, *step.CLICommand, result, i)
-			handleSleep(step.CLICommand.SleepAfterMs, send)
+			results[i] = runWithRetry(i, step, variables, send, func() api.CLIStepResult {
+				result := runCLICommand(command, variables)
+				result.JqOutputs = collectJqOutputs(command, result)
+				return api.CLIStepResult{CLICommandResult: &result}
+			})
+			sendCLICommandResults(send, command, *results[i].CLICommandResult, i)
+			handleSleep(command.SleepAfterMs, send)
 
 		case step.HTTPRequest != nil:
 			fullURL := strings.Replace(step.HTTPRequest.Request.FullURL, api.BaseURLPlaceholder, baseURL, 1)
@@ -59,19 +96,135 @@ func CLIChecks(cliData api.CLIData, overrideBaseURL string, send func(tea.Msg))
 				NoPenaltyOnFail: step.NoPenaltyOnFail,
 			})
 
-			result := runHTTPRequest(client, baseURL, variables, *step.HTTPRequest)
-			results[i].HTTPRequestResult = &result
-			sendHTTPRequestResults(send, *step.HTTPRequest, result, i)
+			results[i] = runWithRetry(i, step, variables, send, func() api.CLIStepResult {
+				result := runHTTPRequest(client, baseURL, variables, *step.HTTPRequest)
+				return api.CLIStepResult{HTTPRequestResult: &result}
+			})
+			sendHTTPRequestResults(send, *step.HTTPRequest, *results[i].HTTPRequestResult, i)
 			handleSleep(step.HTTPRequest.SleepAfterMs, send)
 
+		case step.HTTPLoad != nil:
+			fullURL := strings.Replace(step.HTTPLoad.Request.FullURL, api.BaseURLPlaceholder, baseURL, 1)
+			send(messages.StartStepMsg{
+				Description:     step.Description,
+				URL:             InterpolateVariables(fullURL, variables),
+				Method:          step.HTTPLoad.Request.Method,
+				Load:            describeHTTPLoad(*step.HTTPLoad),
+				NoPenaltyOnFail: step.NoPenaltyOnFail,
+			})
+
+			results[i] = runWithRetry(i, step, variables, send, func() api.CLIStepResult {
+				result := runHTTPLoad(client, baseURL, *step.HTTPLoad, variables)
+				return api.CLIStepResult{HTTPLoadResult: &result}
+			})
+			sendHTTPLoadResults(send, *step.HTTPLoad, *results[i].HTTPLoadResult, i)
+			handleSleep(step.HTTPLoad.SleepAfterMs, send)
+
+		case step.BackgroundProcess != nil:
+			send(messages.StartStepMsg{
+				Description:     step.Description,
+				CMD:             step.BackgroundProcess.Command,
+				Background:      true,
+				NoPenaltyOnFail: step.NoPenaltyOnFail,
+			})
+
+			process, result := startBackgroundProcess(i, *step.BackgroundProcess, variables)
+			if process != nil {
+				backgroundProcesses = append(backgroundProcesses, process)
+			}
+			results[i].BackgroundProcessResult = &result
+			send(messages.ResolveStepMsg{
+				Index:  i,
+				Result: &api.CLIStepResult{BackgroundProcessResult: &result},
+			})
+			handleSleep(step.BackgroundProcess.SleepAfterMs, send)
+
+		case step.Session != nil:
+			send(messages.StartStepMsg{
+				Description:     step.Description,
+				CMD:             step.Session.Command,
+				NoPenaltyOnFail: step.NoPenaltyOnFail,
+			})
+
+			results[i] = runWithRetry(i, step, variables, send, func() api.CLIStepResult {
+				result := runSession(*step.Session, variables)
+				return api.CLIStepResult{SessionResult: &result}
+			})
+			sendSessionResults(send, *step.Session, *results[i].SessionResult, i)
+			handleSleep(step.Session.SleepAfterMs, send)
+
+		case step.Filesystem != nil:
+			paths := make([]string, 0, len(step.Filesystem.Tests))
+			for _, test := range step.Filesystem.Tests {
+				paths = append(paths, InterpolateVariables(test.Path, variables))
+			}
+			send(messages.StartStepMsg{
+				Description:     step.Description,
+				Paths:           paths,
+				NoPenaltyOnFail: step.NoPenaltyOnFail,
+			})
+
+			results[i] = runWithRetry(i, step, variables, send, func() api.CLIStepResult {
+				result := runFilesystemChecks(*step.Filesystem, variables)
+				return api.CLIStepResult{FilesystemResult: &result}
+			})
+			sendFilesystemResults(send, *step.Filesystem, *results[i].FilesystemResult, i)
+			handleSleep(step.Filesystem.SleepAfterMs, send)
+
+		case step.Parallel != nil:
+			if err := checkParallelCopies(*step.Parallel); err != nil {
+				return nil, err
+			}
+			send(messages.StartStepMsg{
+				Description:     step.Description,
+				Parallel:        len(parallelCopies(*step.Parallel)),
+				NoPenaltyOnFail: step.NoPenaltyOnFail,
+			})
+
+			stepVariables := maps.Clone(variables)
+			results[i] = runWithRetry(i, step, variables, send, func() api.CLIStepResult {
+				result := runParallel(client, baseURL, *step.Parallel, variables)
+				return api.CLIStepResult{ParallelResult: &result}
+			})
+			sendParallelResults(send, *step.Parallel, *results[i].ParallelResult, baseURL, stepVariables, i)
+			handleSleep(step.Parallel.SleepAfterMs, send)
+
+		case step.WaitFor != nil:
+			send(messages.StartStepMsg{
+				Description:     step.Description,
+				WaitFor:         describeWaitFor(*step.WaitFor, variables),
+				NoPenaltyOnFail: step.NoPenaltyOnFail,
+			})
+
+			result := runWaitFor(*step.WaitFor, variables, send)
+			results[i].WaitForResult = &result
+			send(messages.ResolveStepMsg{
+				Index:  i,
+				Result: &api.CLIStepResult{WaitForResult: &result},
+			})
+
 		default:
 			return nil, errors.New("unable to run lesson: missing step")
 		}
 	}
 
+	stopBackgroundProcesses(backgroundProcesses, results, send)
 	return results, nil
 }
 
+// stopBackgroundProcesses tears down every background process once the other
+// steps are done and updates their results with the captured logs.
+func stopBackgroundProcesses(processes []*backgroundProcess, results []api.CLIStepResult, send func(tea.Msg)) {
+	for _, process := range processes {
+		result := process.stop()
+		results[process.stepIndex].BackgroundProcessResult = &result
+		send(messages.ResolveStepMsg{
+			Index:  process.stepIndex,
+			Result: &api.CLIStepResult{BackgroundProcessResult: &result},
+		})
+	}
+}
+
 func sendCLICommandResults(send func(tea.Msg), cmd api.CLIStepCLICommand, result api.CLICommandResult, index int) {
 	for _, test := range cmd.Tests {
 		send(messages.StartTestMsg{Text: prettyPrintCLICommand(test, result.Variables)})
@@ -92,6 +245,100 @@ func sendCLICommandResults(send func(tea.Msg), cmd api.CLIStepCLICommand, result
 	})
 }
 
+func sendSessionResults(send func(tea.Msg), session api.CLIStepSession, result api.SessionResult, index int) {
+	for _, action := range session.Actions {
+		send(messages.StartTestMsg{Text: prettyPrintSessionAction(action, result.Variables)})
+	}
+	for _, test := range session.Tests {
+		send(messages.StartTestMsg{Text: prettyPrintCLICommand(test, result.Variables)})
+	}
+
+	for j := range len(session.Actions) + len(session.Tests) {
+		send(messages.ResolveTestMsg{
+			StepIndex: index,
+			TestIndex: j,
+		})
+	}
+
+	send(messages.ResolveStepMsg{
+		Index: index,
+		Result: &api.CLIStepResult{
+			SessionResult: &result,
+		},
+	})
+}
+
+func sendFilesystemResults(send func(tea.Msg), filesystem api.CLIStepFilesystem, result api.FilesystemResult, index int) {
+	for _, test := range filesystem.Tests {
+		send(messages.StartTestMsg{Text: prettyPrintFilesystemTest(test, result.Variables)})
+	}
+
+	for j := range filesystem.Tests {
+		send(messages.ResolveTestMsg{
+			StepIndex: index,
+			TestIndex: j,
+		})
+	}
+
+	send(messages.ResolveStepMsg{
+		Index: index,
+		Result: &api.CLIStepResult{
+			FilesystemResult: &result,
+		},
+	})
+}
+
+func sendParallelResults(
+	send func(tea.Msg),
+	parallel api.CLIStepParallel,
+	result api.ParallelResult,
+	baseURL string,
+	variables map[string]string,
+	index int,
+) {
+	copies := parallelCopies(parallel)
+	for j, step := range copies {
+		send(messages.StartTestMsg{Text: prettyPrintParallelStep(j, len(copies), step, baseURL, variables)})
+	}
+	for _, test := range parallel.Tests {
+		send(messages.StartTestMsg{Text: prettyPrintParallelTest(test)})
+	}
+
+	for j := range len(copies) + len(parallel.Tests) {
+		send(messages.ResolveTestMsg{
+			StepIndex: index,
+			TestIndex: j,
+		})
+	}
+
+	send(messages.ResolveStepMsg{
+		Index: index,
+		Result: &api.CLIStepResult{
+			ParallelResult: &result,
+		},
+	})
+}
+
+func sendHTTPLoadResults(send func(tea.Msg), load api.CLIStepHTTPLoad, result api.HTTPLoadResult, index int) {
+	for _, test := range load.Tests {
+		send(messages.StartTestMsg{Text: prettyPrintHTTPLoadTest(test)})
+	}
+
+	for j := range load.Tests {
+		send(messages.ResolveTestMsg{
+			StepIndex: index,
+			TestIndex: j,
+		})
+	}
+
+	send(messages.ResolveStepMsg{
+		Index: index,
+		Result: &api.CLIStepResult{
+			HTTPLoadResult: &result,
+		},
+	})
+}
+
 func sendHTTPRequestResults(send func(tea.Msg), req api.CLIStepHTTPRequest, result api.HTTPRequestResult, index int) {
 	for _, test := range req.Tests {
 		send(messages.StartTestMsg{Text: prettyPrintHTTPTest(test, result.Variables)})
@@ -112,7 +359,10 @@ func sendHTTPRequestResults(send func(tea.Msg), req api.CLIStepHTTPRequest, resu
 	})
 }
 
-func ApplySubmissionResults(cliData api.CLIData, failure *api.StructuredErrCLI, send func(tea.Msg)) {
+// ApplySubmissionResults resolves every step and test up to the failure. The
+// results are the ones that were submitted; loop steps take their iteration
+// count from them.
+func ApplySubmissionResults(cliData api.CLIData, results []api.CLIStepResult, failure *api.StructuredErrCLI, send func(tea.Msg)) {
 	for i, step := range cliData.Steps {
 		stepPass := true
 		isFailedStep := false
@@ -127,10 +377,20 @@ func ApplySubmissionResults(cliData api.CLIData, failure *api.StructuredErrCLI,
 		})
 
 		testCount := 0
-		if step.CLICommand != nil {
+		if isLoopStep(step) {
+			testCount = loopTestCount(step, results, i)
+		} else if step.CLICommand != nil {
 			testCount = len(step.CLICommand.Tests)
 		} else if step.HTTPRequest != nil {
 			testCount = len(step.HTTPRequest.Tests)
+		} else if step.HTTPLoad != nil {
+			testCount = len(step.HTTPLoad.Tests)
+		} else if step.Filesystem != nil {
+			testCount = len(step.Filesystem.Tests)
+		} else if step.Session != nil {
+			testCount = len(step.Session.Actions) + len(step.Session.Tests)
+		} else if step.Parallel != nil {
+			testCount = len(parallelCopies(*step.Parallel)) + len(step.Parallel.Tests)
 		}
 
 		for j := range testCount {
@@ -4,8 +4,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	api "github.com/bootdotdev/bootdev/client"
 	"github.com/bootdotdev/bootdev/messages"
@@ -48,7 +52,7 @@ func TestCLIChecksInterpolatesResolvedBaseURLInCommands(t *testing.T) {
 					},
 				}},
 			}
-			results, err := CLIChecks(cliData, tt.overrideBaseURL, func(tea.Msg) {})
+			results, err := CLIChecks(cliData, tt.overrideBaseURL, 0, func(tea.Msg) {})
 			if err != nil {
 				t.Fatalf("CLIChecks() error = %v", err)
 			}
@@ -84,7 +88,7 @@ func TestCLIChecksUsesOverrideParameterForHTTPRequestPreview(t *testing.T) {
 		}},
 	}
 	var sent []tea.Msg
-	results, err := CLIChecks(cliData, server.URL+"/", func(msg tea.Msg) {
+	results, err := CLIChecks(cliData, server.URL+"/", 0, func(msg tea.Msg) {
 		sent = append(sent, msg)
 	})
 	if err != nil {
@@ -123,7 +127,7 @@ func TestCLIChecksReturnsManifestErrors(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := CLIChecks(tt.data, "", func(tea.Msg) {})
+			_, err := CLIChecks(tt.data, "", 0, func(tea.Msg) {})
 			if err == nil || !strings.Contains(err.Error(), tt.want) {
 				t.Fatalf("CLIChecks() error = %v, want error containing %q", err, tt.want)
 			}
@@ -131,13 +135,149 @@ func TestCLIChecksReturnsManifestErrors(t *testing.T) {
 	}
 }
 
+func TestCLIChecksStopsBackgroundProcessAndCapturesLogs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix shell commands")
+	}
+
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{BackgroundProcess: &api.CLIStepBackgroundProcess{Command: `echo "listening on ${baseURL}"; sleep 30`}},
+		{CLICommand: &api.CLIStepCLICommand{Command: `sleep 0.2; echo done`}},
+	}}
+
+	start := time.Now()
+	results, err := CLIChecks(cliData, "http://localhost:8080", 0, func(tea.Msg) {})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("CLIChecks() took %s, want background process stopped on return", elapsed)
+	}
+
+	result := results[0].BackgroundProcessResult
+	if result == nil {
+		t.Fatal("missing background process result")
+	}
+	if result.ExitedEarly {
+		t.Fatalf("background process unexpectedly exited early: %#v", result)
+	}
+	if result.Stdout != "listening on http://localhost:8080" {
+		t.Fatalf("background stdout = %q, want captured logs", result.Stdout)
+	}
+	if failure := EvaluateCLIResults(cliData, results); failure != nil {
+		t.Fatalf("unexpected failure: %#v", failure)
+	}
+}
+
+func TestCLIChecksReportsBackgroundProcessThatExitsEarly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix shell commands")
+	}
+
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{BackgroundProcess: &api.CLIStepBackgroundProcess{Command: `echo "port in use" >&2; exit 3`}},
+		{CLICommand: &api.CLIStepCLICommand{Command: `sleep 0.2`}},
+	}}
+
+	results, err := CLIChecks(cliData, "", 0, func(tea.Msg) {})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	result := results[0].BackgroundProcessResult
+	if !result.ExitedEarly || result.ExitCode != 3 {
+		t.Fatalf("background result = %#v, want early exit with code 3", result)
+	}
+	if result.Stderr != "port in use" {
+		t.Fatalf("background stderr = %q, want captured logs", result.Stderr)
+	}
+	failure := EvaluateCLIResults(cliData, results)
+	if failure == nil || failure.FailedStepIndex != 0 {
+		t.Fatalf("failure = %#v, want failure on background step", failure)
+	}
+}
+
+func TestCLIChecksRetriesStepUntilTestsPass(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"id":"stale"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"fresh"}`))
+	}))
+	defer server.Close()
+
+	cliData := api.CLIData{Steps: []api.CLIStep{{
+		Retry: &api.CLIStepRetry{Attempts: 5, DelayMs: intPtr(1), ExponentialBackoff: true},
+		HTTPRequest: &api.CLIStepHTTPRequest{
+			Request:           api.HTTPRequest{Method: http.MethodGet, FullURL: api.BaseURLPlaceholder + "/jobs/1"},
+			Tests:             []api.HTTPRequestTest{{StatusCode: intPtr(http.StatusOK)}},
+			ResponseVariables: []api.HTTPRequestResponseVariable{{Name: "id", Path: ".id"}},
+		},
+	}}}
+
+	var retries []messages.RetryStepMsg
+	results, err := CLIChecks(cliData, server.URL, 0, func(msg tea.Msg) {
+		if msg, ok := msg.(messages.RetryStepMsg); ok {
+			retries = append(retries, msg)
+		}
+	})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	if requests != 3 {
+		t.Fatalf("requests = %d, want 3", requests)
+	}
+	want := []messages.RetryStepMsg{
+		{Index: 0, Attempt: 1, MaxAttempts: 5, Error: "expected status code 200, got 503", DelayMs: 1},
+		{Index: 0, Attempt: 2, MaxAttempts: 5, Error: "expected status code 200, got 503", DelayMs: 2},
+	}
+	if !reflect.DeepEqual(retries, want) {
+		t.Fatalf("retries = %#v, want %#v", retries, want)
+	}
+	if got := results[0].HTTPRequestResult; got.StatusCode != http.StatusOK || got.Variables["id"] != "fresh" {
+		t.Fatalf("final result = %#v, want the passing attempt", got)
+	}
+}
+
+func TestCLIChecksSubmitsLastAttemptWhenRetriesRunOut(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{{
+		Retry: &api.CLIStepRetry{Attempts: 2},
+		CLICommand: &api.CLIStepCLICommand{
+			Command: `echo nope`,
+			Tests:   []api.CLICommandTest{{StdoutContainsAll: []string{"yes"}}},
+		},
+	}}}
+
+	retries := 0
+	results, err := CLIChecks(cliData, "", 0, func(msg tea.Msg) {
+		if _, ok := msg.(messages.RetryStepMsg); ok {
+			retries++
+		}
+	})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	if retries != 1 {
+		t.Fatalf("retries = %d, want 1", retries)
+	}
+	if failure := EvaluateCLIResults(cliData, results); failure == nil {
+		t.Fatal("expected the final attempt to fail")
+	}
+}
+
 func TestApplySubmissionResultsMarksAllStepsAndTestsPassedWhenNoFailure(t *testing.T) {
 	cliData := api.CLIData{Steps: []api.CLIStep{
 		{CLICommand: &api.CLIStepCLICommand{Tests: []api.CLICommandTest{{}, {}}}},
 		{HTTPRequest: &api.CLIStepHTTPRequest{Tests: []api.HTTPRequestTest{{}}}},
 	}}
 
-	got := applySubmissionResultsMessages(cliData, nil)
+	got := applySubmissionResultsMessages(cliData, nil, nil)
 	want := []tea.Msg{
 		messages.ResolveStepMsg{Index: 0, Passed: boolPtr(true)},
 		messages.ResolveTestMsg{StepIndex: 0, TestIndex: 0, Passed: boolPtr(true)},
@@ -157,7 +297,7 @@ func TestApplySubmissionResultsStopsAfterFailedCLITest(t *testing.T) {
 	}}
 	failure := &api.StructuredErrCLI{FailedStepIndex: 1, FailedTestIndex: 1}
 
-	got := applySubmissionResultsMessages(cliData, failure)
+	got := applySubmissionResultsMessages(cliData, nil, failure)
 	want := []tea.Msg{
 		messages.ResolveStepMsg{Index: 0, Passed: boolPtr(true)},
 		messages.ResolveTestMsg{StepIndex: 0, TestIndex: 0, Passed: boolPtr(true)},
@@ -169,9 +309,25 @@ func TestApplySubmissionResultsStopsAfterFailedCLITest(t *testing.T) {
 	assertMessages(t, got, want)
 }
 
-func applySubmissionResultsMessages(cliData api.CLIData, failure *api.StructuredErrCLI) []tea.Msg {
+func TestApplySubmissionResultsResolvesEachForeachIteration(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{Foreach: `["a","b"]`, CLICommand: &api.CLIStepCLICommand{Command: "echo ${item}"}},
+	}}
+	results := []api.CLIStepResult{{Iterations: make([]api.CLIStepResult, 2)}}
+
+	got := applySubmissionResultsMessages(cliData, results, nil)
+	want := []tea.Msg{
+		messages.ResolveStepMsg{Index: 0, Passed: boolPtr(true)},
+		messages.ResolveTestMsg{StepIndex: 0, TestIndex: 0, Passed: boolPtr(true)},
+		messages.ResolveTestMsg{StepIndex: 0, TestIndex: 1, Passed: boolPtr(true)},
+	}
+
+	assertMessages(t, got, want)
+}
+
+func applySubmissionResultsMessages(cliData api.CLIData, results []api.CLIStepResult, failure *api.StructuredErrCLI) []tea.Msg {
 	var msgs []tea.Msg
-	ApplySubmissionResults(cliData, failure, func(msg tea.Msg) {
+	ApplySubmissionResults(cliData, results, failure, func(msg tea.Msg) {
 		msgs = append(msgs, msg)
 	})
 	return msgs
@@ -188,3 +344,228 @@ func assertMessages(t *testing.T, got []tea.Msg, want []tea.Msg) {
 func boolPtr(v bool) *bool {
 	return &v
 }
+
+func TestCLIChecksSkipsStepsWhoseConditionFails(t *testing.T) {
+	otherOS := "plan9"
+	if runtime.GOOS == otherOS {
+		otherOS = "linux"
+	}
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{CLICommand: &api.CLIStepCLICommand{
+			Command:         `echo mode=fast`,
+			StdoutVariables: []api.CLICommandStdoutVariable{{Name: "mode", Regex: `mode=(\w+)`}},
+		}},
+		{
+			When: &api.CLIStepWhen{VariableEquals: &api.WhenVariableEquals{Name: "mode", Value: "slow"}},
+			CLICommand: &api.CLIStepCLICommand{
+				Command: `exit 1`,
+				Tests:   []api.CLICommandTest{{ExitCode: intPtr(0)}},
+			},
+		},
+		{
+			When:       &api.CLIStepWhen{PreviousStep: &api.WhenPreviousStep{Passed: boolPtr(true)}},
+			CLICommand: &api.CLIStepCLICommand{Command: `echo unreachable`},
+		},
+		{
+			When:       &api.CLIStepWhen{OS: []string{otherOS}},
+			CLICommand: &api.CLIStepCLICommand{Command: `echo unreachable`},
+		},
+		{
+			When:       &api.CLIStepWhen{VariableExists: "mode", OS: []string{runtime.GOOS}},
+			CLICommand: &api.CLIStepCLICommand{Command: `echo ran`},
+		},
+	}}
+
+	var skips []messages.SkipStepMsg
+	results, err := CLIChecks(cliData, "", 0, func(msg tea.Msg) {
+		if msg, ok := msg.(messages.SkipStepMsg); ok {
+			skips = append(skips, msg)
+		}
+	})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	want := []messages.SkipStepMsg{
+		{Index: 1, Reason: `variable mode isn't "slow"`},
+		{Index: 2, Reason: "the previous step was skipped"},
+		{Index: 3, Reason: "only runs on " + otherOS},
+	}
+	if !reflect.DeepEqual(skips, want) {
+		t.Fatalf("skips = %#v, want %#v", skips, want)
+	}
+	for _, i := range []int{1, 2, 3} {
+		if !results[i].Skipped || results[i].CLICommandResult != nil {
+			t.Fatalf("results[%d] = %#v, want a skipped result", i, results[i])
+		}
+	}
+	if got := results[4].CLICommandResult; got == nil || !strings.Contains(got.Stdout, "ran") {
+		t.Fatalf("results[4] = %#v, want the step to run", results[4])
+	}
+	if failure := EvaluateCLIResults(cliData, results); failure != nil {
+		t.Fatalf("EvaluateCLIResults() = %#v, want skipped steps not to fail", failure)
+	}
+}
+
+func TestPreviousStepConditionChecksExitCodeAndPassed(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{
+		{CLICommand: &api.CLIStepCLICommand{Tests: []api.CLICommandTest{{ExitCode: intPtr(0)}}}},
+		{When: &api.CLIStepWhen{PreviousStep: &api.WhenPreviousStep{Passed: boolPtr(false), ExitCode: intPtr(2)}}},
+	}}
+	results := []api.CLIStepResult{{CLICommandResult: &api.CLICommandResult{ExitCode: 2}}, {}}
+
+	if run, reason := shouldRunStep(1, cliData, results, nil); !run {
+		t.Fatalf("shouldRunStep() = false (%s), want true", reason)
+	}
+
+	results[0].CLICommandResult.ExitCode = 0
+	if run, reason := shouldRunStep(1, cliData, results, nil); run || reason != "the previous step passed" {
+		t.Fatalf("shouldRunStep() = %v, %q, want false because the previous step passed", run, reason)
+	}
+
+	if run, reason := shouldRunStep(0, api.CLIData{Steps: cliData.Steps[1:]}, results, nil); run || reason != "there's no previous step" {
+		t.Fatalf("shouldRunStep() = %v, %q, want false for the first step", run, reason)
+	}
+}
+
+func TestCLIChecksRunsCommandVariantForThisOS(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{{CLICommand: &api.CLIStepCLICommand{
+		Command:  "echo default",
+		Commands: map[string]string{runtime.GOOS: "echo native"},
+	}}}}
+
+	var started []string
+	results, err := CLIChecks(cliData, "", 0, func(msg tea.Msg) {
+		if msg, ok := msg.(messages.StartStepMsg); ok {
+			started = append(started, msg.CMD)
+		}
+	})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(started, []string{"echo native"}) {
+		t.Fatalf("started = %q, want the native variant", started)
+	}
+	if got := results[0].CLICommandResult; got.Command.Command != "echo native" || !strings.Contains(got.Stdout, "native") {
+		t.Fatalf("result = %#v, want the native variant to run", got)
+	}
+
+	cliData.Steps[0].CLICommand = &api.CLIStepCLICommand{Commands: map[string]string{"plan9": "echo"}}
+	if runtime.GOOS != "plan9" {
+		if _, err := CLIChecks(cliData, "", 0, func(tea.Msg) {}); err == nil || !strings.Contains(err.Error(), "no command for "+runtime.GOOS) {
+			t.Fatalf("CLIChecks() error = %v, want a missing variant error", err)
+		}
+	}
+}
+
+func TestCLIChecksRunsParallelStepsTogether(t *testing.T) {
+	const requests = 3
+	var mu sync.Mutex
+	arrived := 0
+	allArrived := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		arrived++
+		first := arrived == 1
+		if arrived == requests {
+			close(allArrived)
+		}
+		mu.Unlock()
+
+		// hold every request until they're all in flight
+		select {
+		case <-allArrived:
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		if !first {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	cliData := api.CLIData{Steps: []api.CLIStep{{Parallel: &api.CLIStepParallel{
+		Steps: []api.ParallelStep{
+			{Count: requests, HTTPRequest: &api.CLIStepHTTPRequest{
+				Request: api.HTTPRequest{Method: http.MethodPost, FullURL: api.BaseURLPlaceholder + "/withdraw"},
+			}},
+			{CLICommand: &api.CLIStepCLICommand{
+				Command:         `echo token=abc`,
+				StdoutVariables: []api.CLICommandStdoutVariable{{Name: "token", Regex: `token=(\w+)`}},
+			}},
+		},
+		Tests: []api.ParallelTest{
+			{StatusCode: intPtr(http.StatusOK), Exactly: intPtr(1)},
+			{StatusCode: intPtr(http.StatusTooManyRequests), AtLeast: intPtr(2)},
+			{ExitCode: intPtr(0), AtLeast: intPtr(1)},
+		},
+	}}}}
+
+	var texts []string
+	results, err := CLIChecks(cliData, server.URL, 0, func(msg tea.Msg) {
+		if msg, ok := msg.(messages.StartTestMsg); ok {
+			texts = append(texts, msg.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("CLIChecks() error = %v", err)
+	}
+
+	wantTexts := []string{
+		"[1/4] POST " + server.URL + "/withdraw",
+		"[2/4] POST " + server.URL + "/withdraw",
+		"[3/4] POST " + server.URL + "/withdraw",
+		"[4/4] Command: echo token=abc",
+		"Expect exactly 1 of the results to return status 200",
+		"Expect at least 2 of the results to return status 429",
+		"Expect at least 1 of the results to exit with code 0",
+	}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Fatalf("test texts = %q, want %q", texts, wantTexts)
+	}
+	if failure := EvaluateCLIResults(cliData, results); failure != nil {
+		t.Fatalf("EvaluateCLIResults() = %#v, want the parallel step to pass", failure)
+	}
+	if got := SecretValues([]string{"token"}, StepResultVariableSets(results[0])...); !slices.Equal(got, []string{"abc"}) {
+		t.Fatalf("secret values = %q, want the token from the parallel command", got)
+	}
+}
+
+func TestCLIChecksRejectsTooManyParallelCopies(t *testing.T) {
+	cliData := api.CLIData{Steps: []api.CLIStep{{Parallel: &api.CLIStepParallel{Steps: []api.ParallelStep{
+		{Count: maxParallelCopies, CLICommand: &api.CLIStepCLICommand{Command: "echo a"}},
+		{CLICommand: &api.CLIStepCLICommand{Command: "echo b"}},
+	}}}}}
+
+	_, err := CLIChecks(cliData, "", 0, func(tea.Msg) {})
+	if err == nil || !strings.Contains(err.Error(), "at most 100 copies") {
+		t.Fatalf("err = %v, want a parallel copy limit error", err)
+	}
+}
+
+func TestEvaluateParallelResultReportsFailingCopyAndCount(t *testing.T) {
+	parallel := api.CLIStepParallel{
+		Steps: []api.ParallelStep{{Count: 2, HTTPRequest: &api.CLIStepHTTPRequest{
+			Tests: []api.HTTPRequestTest{{StatusCode: intPtr(http.StatusOK)}},
+		}}},
+		Tests: []api.ParallelTest{{StatusCode: intPtr(http.StatusOK), AtMost: intPtr(1)}},
+	}
+	result := api.ParallelResult{Results: []api.CLIStepResult{
+		{HTTPRequestResult: &api.HTTPRequestResult{StatusCode: http.StatusOK}},
+		{HTTPRequestResult: &api.HTTPRequestResult{StatusCode: http.StatusConflict}},
+	}}
+
+	failure := evaluateParallelResult(2, parallel, result)
+	if failure == nil || failure.FailedTestIndex != 1 || failure.ErrorMessage != "parallel step 2: expected status code 200, got 409" {
+		t.Fatalf("failure = %#v, want the second copy to fail", failure)
+	}
+
+	result.Results[1].HTTPRequestResult.StatusCode = http.StatusOK
+	parallel.Steps[0].HTTPRequest.Tests = nil
+	failure = evaluateParallelResult(2, parallel, result)
+	if failure == nil || failure.FailedTestIndex != 2 || failure.ErrorMessage != "expected at most 1 of 2 results to return status 200, got 2" {
+		t.Fatalf("failure = %#v, want the aggregate test to fail", failure)
+	}
+}
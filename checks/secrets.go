@@ -0,0 +1,173 @@
+package checks
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/goccy/go-json"
+)
+
+const redactedSecret = "[secret]"
+
+// SecretVariableNames returns the names of every variable the lesson marks as
+// secret. ${item} is secret too when a foreach step loops over a secret.
+func SecretVariableNames(cliData api.CLIData) []string {
+	var names []string
+	for _, step := range cliData.Steps {
+		names = append(names, stepVariableNames(step, true)...)
+	}
+	for _, step := range cliData.Steps {
+		if step.Foreach != "" && slices.ContainsFunc(InterpolationNames(step.Foreach), func(name string) bool {
+			return slices.Contains(names, name)
+		}) {
+			names = append(names, loopItemVariable)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// stepVariableNames returns the names of the variables a step captures,
+// including its parallel children's.
+func stepVariableNames(step api.CLIStep, secretOnly bool) []string {
+	names := capturedVariableNames(step.CLICommand, step.HTTPRequest, secretOnly)
+	if step.Parallel != nil {
+		for _, parallelStep := range step.Parallel.Steps {
+			names = append(names, capturedVariableNames(parallelStep.CLICommand, parallelStep.HTTPRequest, secretOnly)...)
+		}
+	}
+	return names
+}
+
+func capturedVariableNames(command *api.CLIStepCLICommand, request *api.CLIStepHTTPRequest, secretOnly bool) []string {
+	var names []string
+	if command != nil {
+		for _, vardef := range command.StdoutVariables {
+			if vardef.Secret || !secretOnly {
+				names = append(names, stdoutVariableNames(vardef)...)
+			}
+		}
+	}
+	if request != nil {
+		for _, vardef := range request.ResponseVariables {
+			if vardef.Secret || !secretOnly {
+				names = append(names, vardef.Name)
+			}
+		}
+		for _, vardef := range request.ResponseHeaderVariables {
+			if vardef.Secret || !secretOnly {
+				names = append(names, vardef.Name)
+			}
+		}
+	}
+	return names
+}
+
+func stdoutVariableNames(vardef api.CLICommandStdoutVariable) []string {
+	if vardef.Name != "" {
+		return []string{vardef.Name}
+	}
+	re, err := regexp.Compile(vardef.Regex)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, name := range re.SubexpNames()[1:] {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SecretValues collects the values the secret names took on in any of the
+// variable sets. A secret ${item} also covers its ${item.<key>} fields.
+func SecretValues(names []string, variableSets ...map[string]string) []string {
+	var values []string
+	for _, variables := range variableSets {
+		for _, name := range names {
+			if value := variables[name]; value != "" && !slices.Contains(values, value) {
+				values = append(values, value)
+			}
+		}
+		if !slices.Contains(names, loopItemVariable) {
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(variables)) {
+			value := variables[name]
+			if strings.HasPrefix(name, loopItemVariable+".") && value != "" && !slices.Contains(values, value) {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// StepResultVariableSets returns the variables a step result was evaluated
+// with. Loop and parallel steps have one set per iteration or copy.
+func StepResultVariableSets(result api.CLIStepResult) []map[string]string {
+	switch {
+	case len(result.Iterations) > 0:
+		var sets []map[string]string
+		for _, iteration := range result.Iterations {
+			sets = append(sets, StepResultVariableSets(iteration)...)
+		}
+		return sets
+	case result.CLICommandResult != nil:
+		return []map[string]string{result.CLICommandResult.Variables}
+	case result.HTTPRequestResult != nil:
+		return []map[string]string{result.HTTPRequestResult.Variables}
+	case result.BackgroundProcessResult != nil:
+		return []map[string]string{result.BackgroundProcessResult.Variables}
+	case result.WaitForResult != nil:
+		return []map[string]string{result.WaitForResult.Variables}
+	case result.SessionResult != nil:
+		return []map[string]string{result.SessionResult.Variables}
+	case result.FilesystemResult != nil:
+		return []map[string]string{result.FilesystemResult.Variables}
+	case result.HTTPLoadResult != nil:
+		return []map[string]string{result.HTTPLoadResult.Variables}
+	case result.ParallelResult != nil:
+		var sets []map[string]string
+		for _, parallelResult := range result.ParallelResult.Results {
+			sets = append(sets, StepResultVariableSets(parallelResult)...)
+		}
+		return sets
+	default:
+		return nil
+	}
+}
+
+// RedactSecrets replaces every secret value in text, including its JSON-escaped
+// form, so it's safe to use on rendered output and raw request bodies alike.
+func RedactSecrets(text string, secrets []string) string {
+	var needles []string
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		needles = append(needles, secret)
+		if encoded, err := json.Marshal(secret); err == nil {
+			if escaped := string(encoded[1 : len(encoded)-1]); escaped != secret {
+				needles = append(needles, escaped)
+			}
+		}
+	}
+	if len(needles) == 0 {
+		return text
+	}
+
+	// the replacer tries needles in order, so a secret that contains another
+	// one has to come first to be redacted in full
+	slices.SortStableFunc(needles, func(a, b string) int {
+		return len(b) - len(a)
+	})
+	oldnew := make([]string, 0, 2*len(needles))
+	for _, needle := range needles {
+		oldnew = append(oldnew, needle, redactedSecret)
+	}
+	return strings.NewReplacer(oldnew...).Replace(text)
+}
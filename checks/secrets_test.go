@@ -0,0 +1,42 @@
+package checks
+
+import (
+	"slices"
+	"testing"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+func TestSecretVariableNames(t *testing.T) {
+	data := api.CLIData{Steps: []api.CLIStep{
+		{CLICommand: &api.CLIStepCLICommand{StdoutVariables: []api.CLICommandStdoutVariable{
+			{Name: "apiKey", Regex: `key=(\w+)`, Secret: true},
+			{Regex: `(?P<user>\w+):(?P<password>\w+)`, Secret: true},
+			{Name: "public", Regex: `id=(\w+)`},
+		}}},
+		{HTTPRequest: &api.CLIStepHTTPRequest{
+			ResponseVariables:       []api.HTTPRequestResponseVariable{{Name: "jwt", Path: ".token", Secret: true}},
+			ResponseHeaderVariables: []api.HTTPRequestResponseHeaderVariable{{Name: "session", Header: "Set-Cookie", Secret: true}},
+		}},
+	}}
+
+	got := SecretVariableNames(data)
+	want := []string{"apiKey", "jwt", "password", "session", "user"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("SecretVariableNames() = %v, want %v", got, want)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	secrets := SecretValues(
+		[]string{"token", "short", "missing"},
+		map[string]string{"token": `abc"123`, "other": "visible"},
+		map[string]string{"short": "abc"},
+	)
+
+	got := RedactSecrets(`token abc"123, json {"t":"abc\"123"}, short abc, visible`, secrets)
+	want := `token [secret], json {"t":"[secret]"}, short [secret], visible`
+	if got != want {
+		t.Fatalf("RedactSecrets() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+const (
+	defaultSessionActionTimeout = 5 * time.Second
+
+	// how long a session gets to exit on its own after the last action
+	sessionExitGracePeriod = time.Second
+)
+
+// sessionTranscript collects everything the session writes to its terminal.
+type sessionTranscript struct {
+	mu      sync.Mutex
+	output  *boundedBuffer
+	updated chan struct{}
+	closed  chan struct{}
+}
+
+func newSessionTranscript() *sessionTranscript {
+	return &sessionTranscript{
+		output:  newBoundedBuffer(maxCLIOutputBytesPerStream),
+		updated: make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (t *sessionTranscript) readFrom(r io.Reader) {
+	defer close(t.closed)
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			t.mu.Lock()
+			_, _ = t.output.Write(buf[:n])
+			t.mu.Unlock()
+			select {
+			case t.updated <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *sessionTranscript) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.ReplaceAll(t.output.String(), "\r\n", "\n")
+}
+
+// expect waits until match finds something in the transcript after offset and
+// returns the offset just past the match.
+func (t *sessionTranscript) expect(offset int, timeout time.Duration, match func(string) (int, bool)) (int, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		output := t.String()
+		if end, ok := match(output[offset:]); ok {
+			return offset + end, nil
+		}
+
+		select {
+		case <-t.updated:
+		case <-t.closed:
+			output = t.String()
+			if end, ok := match(output[offset:]); ok {
+				return offset + end, nil
+			}
+			return offset, errors.New("session ended")
+		case <-timer.C:
+			return offset, fmt.Errorf("timed out after %s", timeout)
+		}
+	}
+}
+
+func runSession(session api.CLIStepSession, variables map[string]string) (result api.SessionResult) {
+	finalCommand := InterpolateVariables(session.Command, variables)
+	result.FinalCommand = finalCommand
+	result.Session = session
+	result.Variables = maps.Clone(variables)
+
+	ctx, cancel := context.WithTimeout(context.Background(), durationFromMs(session.TimeoutMs, defaultCLICommandTimeout))
+	defer cancel()
+
+	cmd := newShellCommand(ctx, finalCommand)
+	terminal, err := startPTY(cmd)
+	if err != nil {
+		result.Err = fmt.Sprintf("failed to start session: %s", err)
+		result.Outcome, result.ExitCode = api.CLICommandInternalFailure, api.ExitCodeInternalFailure
+		return result
+	}
+	defer terminal.Close()
+
+	transcript := newSessionTranscript()
+	go transcript.readFrom(terminal)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	offset := 0
+	for _, action := range session.Actions {
+		offset, err = runSessionAction(terminal, transcript, offset, action, variables)
+		if err != nil {
+			result.Err = err.Error()
+			break
+		}
+		result.ActionsCompleted++
+	}
+
+	if result.Err == "" {
+		// ask the program to exit the way a user would, with end-of-input
+		_, _ = terminal.Write([]byte{4})
+	}
+
+	var waitErr error
+	select {
+	case waitErr = <-done:
+	case <-time.After(sessionExitGracePeriod):
+		cancel()
+		waitErr = <-done
+	}
+
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	result.Outcome, result.ExitCode, result.Signal = commandOutcome(waitErr, timedOut)
+	if timedOut && result.Err == "" {
+		result.Err = "session timed out"
+	}
+
+	// the reader gets EIO once the process is gone; make sure it's drained
+	terminal.Close()
+	<-transcript.closed
+	result.Stdout = strings.TrimRight(transcript.String(), " \n\t\r")
+	return result
+}
+
+func runSessionAction(
+	terminal io.Writer,
+	transcript *sessionTranscript,
+	offset int,
+	action api.SessionAction,
+	variables map[string]string,
+) (int, error) {
+	timeout := durationFromMs(action.TimeoutMs, defaultSessionActionTimeout)
+
+	switch {
+	case action.Send != nil:
+		line := InterpolateVariables(*action.Send, variables)
+		if _, err := io.WriteString(terminal, line+"\n"); err != nil {
+			return offset, fmt.Errorf("failed to send %q: %s", line, err)
+		}
+		return offset, nil
+
+	case action.Expect != nil:
+		needle := InterpolateVariables(*action.Expect, variables)
+		end, err := transcript.expect(offset, timeout, func(output string) (int, bool) {
+			i := strings.Index(output, needle)
+			return i + len(needle), i >= 0
+		})
+		if err != nil {
+			return offset, fmt.Errorf("expected output to contain %q: %s", needle, err)
+		}
+		return end, nil
+
+	case action.ExpectRegex != nil:
+		pattern := InterpolateVariables(*action.ExpectRegex, variables)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return offset, fmt.Errorf("invalid session action configuration")
+		}
+		end, err := transcript.expect(offset, timeout, func(output string) (int, bool) {
+			loc := re.FindStringIndex(output)
+			if loc == nil {
+				return 0, false
+			}
+			return loc[1], true
+		})
+		if err != nil {
+			return offset, fmt.Errorf("expected output to match %q: %s", pattern, err)
+		}
+		return end, nil
+
+	default:
+		return offset, fmt.Errorf("invalid session action configuration")
+	}
+}
+
+func prettyPrintSessionAction(action api.SessionAction, variables map[string]string) string {
+	switch {
+	case action.Send != nil:
+		return fmt.Sprintf("Send '%s'", InterpolateVariables(*action.Send, variables))
+	case action.Expect != nil:
+		return fmt.Sprintf("Expect output '%s'", InterpolateVariables(*action.Expect, variables))
+	case action.ExpectRegex != nil:
+		return fmt.Sprintf("Expect output matching '%s'", InterpolateVariables(*action.ExpectRegex, variables))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,125 @@
+package checks
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+const echoREPL = `printf '> '; while IFS= read -r line; do echo "you said: $line"; printf '> '; done`
+
+func TestRunSessionSendsAndExpectsInOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sessions need a unix pseudo-terminal")
+	}
+
+	session := api.CLIStepSession{
+		Command: echoREPL,
+		Actions: []api.SessionAction{
+			{Expect: stringPtr("> ")},
+			{Send: stringPtr("hello ${name}")},
+			{ExpectRegex: stringPtr(`you said: hello \w+`)},
+			{Expect: stringPtr("> ")},
+		},
+		Tests: []api.CLICommandTest{{StdoutContainsAll: []string{"you said: hello Boots"}}},
+	}
+	result := runSession(session, map[string]string{"name": "Boots"})
+
+	if result.Err != "" {
+		t.Fatalf("unexpected session error: %s\n%s", result.Err, result.Stdout)
+	}
+	if result.ActionsCompleted != len(session.Actions) {
+		t.Fatalf("actions completed = %d, want %d", result.ActionsCompleted, len(session.Actions))
+	}
+	if strings.Contains(result.Stdout, "\r\n") {
+		t.Fatalf("transcript = %q, want normalized newlines", result.Stdout)
+	}
+	if failure := evaluateSessionResult(0, session, result); failure != nil {
+		t.Fatalf("unexpected failure: %#v", failure)
+	}
+}
+
+func TestRunSessionReportsFailedExpectAtItsIndex(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sessions need a unix pseudo-terminal")
+	}
+
+	session := api.CLIStepSession{
+		Command: echoREPL,
+		Actions: []api.SessionAction{
+			{Send: stringPtr("hello")},
+			{Expect: stringPtr("goodbye"), TimeoutMs: intPtr(200)},
+		},
+		Tests: []api.CLICommandTest{{StdoutContainsAll: []string{"hello"}}},
+	}
+	result := runSession(session, map[string]string{})
+
+	if result.ActionsCompleted != 1 {
+		t.Fatalf("actions completed = %d, want 1", result.ActionsCompleted)
+	}
+	failure := evaluateSessionResult(0, session, result)
+	if failure == nil || failure.FailedTestIndex != 1 {
+		t.Fatalf("failure = %#v, want failure at action 2", failure)
+	}
+	if !strings.Contains(failure.ErrorMessage, `expected output to contain "goodbye": timed out after 200ms`) {
+		t.Fatalf("error = %q, want expect timeout", failure.ErrorMessage)
+	}
+}
+
+func TestEvaluateSessionResultOffsetsTestIndexesPastActions(t *testing.T) {
+	session := api.CLIStepSession{
+		Actions: []api.SessionAction{{Send: stringPtr("help")}, {Expect: stringPtr("Usage")}},
+		Tests:   []api.CLICommandTest{{StdoutContainsAll: []string{"exit"}}},
+	}
+	result := api.SessionResult{ActionsCompleted: 2, Stdout: "help\nUsage: ..."}
+
+	failure := evaluateSessionResult(3, session, result)
+	if failure == nil || failure.FailedStepIndex != 3 || failure.FailedTestIndex != 2 {
+		t.Fatalf("failure = %#v, want step 3 test 2", failure)
+	}
+}
+
+func TestRunSessionRecordsSignalWhenKilled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sessions need a unix pseudo-terminal")
+	}
+
+	// sleep ignores end-of-input, so the session is killed after the grace period
+	session := api.CLIStepSession{
+		Command: "sleep 30",
+		Tests:   []api.CLICommandTest{{ExitSignal: stringPtr("SIGKILL")}},
+	}
+	result := runSession(session, map[string]string{})
+
+	if result.Outcome != api.CLICommandSignaled || result.Signal != "SIGKILL" {
+		t.Fatalf("outcome = %q signal = %q, want signaled by SIGKILL", result.Outcome, result.Signal)
+	}
+	if failure := evaluateSessionResult(0, session, result); failure != nil {
+		t.Fatalf("unexpected failure: %#v", failure)
+	}
+}
+
+func TestRunSessionExpectDoesNotMatchSentInput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sessions need a unix pseudo-terminal")
+	}
+
+	// cat > /dev/null prints nothing, so only an echo of the input could match
+	session := api.CLIStepSession{
+		Command: "cat > /dev/null",
+		Actions: []api.SessionAction{
+			{Send: stringPtr("secretword")},
+			{Expect: stringPtr("secretword"), TimeoutMs: intPtr(200)},
+		},
+	}
+	result := runSession(session, map[string]string{})
+
+	if result.ActionsCompleted != 1 {
+		t.Fatalf("actions completed = %d, want the expect to fail", result.ActionsCompleted)
+	}
+	if strings.Contains(result.Stdout, "secretword") {
+		t.Fatalf("transcript = %q, want no echoed input", result.Stdout)
+	}
+}
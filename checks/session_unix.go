@@ -0,0 +1,49 @@
+//go:build !windows
+
+package checks
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+)
+
+// startPTY starts the command attached to a new pseudo-terminal and returns its
+// controlling side. The terminal doesn't echo input, so the transcript only has
+// what the program itself printed.
+func startPTY(cmd *exec.Cmd) (*os.File, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer tty.Close()
+
+	if err := disableEcho(tty); err != nil {
+		ptmx.Close()
+		return nil, err
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	// the command gets its own session with the pty as its controlling
+	// terminal, which already puts it in its own process group, and setpgid
+	// fails for a session leader
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	if err := cmd.Start(); err != nil {
+		ptmx.Close()
+		return nil, err
+	}
+	return ptmx, nil
+}
+
+func disableEcho(tty *os.File) error {
+	fd := int(tty.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return err
+	}
+	termios.Lflag &^= unix.ECHO
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, termios)
+}
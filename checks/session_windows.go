@@ -0,0 +1,13 @@
+//go:build windows
+
+package checks
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func startPTY(cmd *exec.Cmd) (*os.File, error) {
+	return nil, errors.New("interactive sessions are not supported on Windows")
+}
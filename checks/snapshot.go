@@ -0,0 +1,174 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+const (
+	snapshotTimestampPlaceholder = "<TIMESTAMP>"
+	snapshotUUIDPlaceholder      = "<UUID>"
+)
+
+var (
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+	snapshotTimestampPattern = regexp.MustCompile(
+		`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?|\b\d{2}:\d{2}:\d{2}(\.\d+)?\b`,
+	)
+	snapshotUUIDPattern = regexp.MustCompile(
+		`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`,
+	)
+	snapshotWhitespacePattern = regexp.MustCompile(`[ \t]+`)
+)
+
+func evaluateStdoutSnapshot(stdout string, test api.StdoutSnapshotTest, caseInsensitive bool) error {
+	actual, err := normalizeSnapshot(stdout, test.Normalizers)
+	if err != nil {
+		return err
+	}
+	expected, err := normalizeSnapshot(test.Golden, test.Normalizers)
+	if err != nil {
+		return err
+	}
+	actual, expected = foldCase(actual, caseInsensitive), foldCase(expected, caseInsensitive)
+
+	diff := unifiedDiff(expected, actual)
+	if diff == "" {
+		return nil
+	}
+	return fmt.Errorf("expected stdout to match snapshot:\n%s", diff)
+}
+
+// normalizeSnapshot applies the configured normalizers in a fixed order: ANSI
+// codes and extra whitespace go first so patterns only see plain text, and
+// custom replacements run last so they see the built-in placeholders.
+func normalizeSnapshot(text string, normalizers api.SnapshotNormalizers) (string, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if normalizers.StripANSI {
+		text = ansiEscapePattern.ReplaceAllString(text, "")
+	}
+	if normalizers.CollapseWhitespace {
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace(snapshotWhitespacePattern.ReplaceAllString(line, " "))
+		}
+		text = strings.Join(lines, "\n")
+	}
+	if normalizers.Timestamps {
+		text = snapshotTimestampPattern.ReplaceAllLiteralString(text, snapshotTimestampPlaceholder)
+	}
+	if normalizers.UUIDs {
+		text = snapshotUUIDPattern.ReplaceAllLiteralString(text, snapshotUUIDPlaceholder)
+	}
+	for _, replacement := range normalizers.Replacements {
+		re, err := regexp.Compile(replacement.Regex)
+		if err != nil {
+			return "", fmt.Errorf("invalid snapshot replacement regex %q: %w", replacement.Regex, err)
+		}
+		text = re.ReplaceAllLiteralString(text, replacement.Placeholder)
+	}
+	return strings.TrimRight(text, " \n\t\r"), nil
+}
+
+// LoadSnapshots resolves snapshot files relative to baseDir and reads them into
+// the tests' golden text. A missing file is an error unless allowMissing is set,
+// which lets the first run with --update-snapshots create it.
+func LoadSnapshots(data *api.CLIData, baseDir string, allowMissing bool) error {
+	for _, test := range snapshotTests(*data) {
+		if test.File == "" {
+			continue
+		}
+		if !filepath.IsAbs(test.File) {
+			test.File = filepath.Join(baseDir, test.File)
+		}
+
+		golden, err := os.ReadFile(test.File)
+		if errors.Is(err, fs.ErrNotExist) && allowMissing {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot %q: %w", test.File, err)
+		}
+		test.Golden = string(golden)
+	}
+	return nil
+}
+
+// UpdateSnapshots writes each step's normalized stdout to its snapshot file and
+// replaces the golden text in data, returning the paths it wrote. Inline goldens
+// live in the lesson itself, so they're left alone and still fail on a mismatch.
+func UpdateSnapshots(data api.CLIData, results []api.CLIStepResult) ([]string, error) {
+	var written []string
+	for stepIndex, step := range data.Steps {
+		if stepIndex >= len(results) {
+			break
+		}
+
+		var stdout string
+		switch {
+		case step.CLICommand != nil && results[stepIndex].CLICommandResult != nil:
+			stdout = results[stepIndex].CLICommandResult.Stdout
+		case step.Session != nil && results[stepIndex].SessionResult != nil:
+			stdout = results[stepIndex].SessionResult.Stdout
+		default:
+			continue
+		}
+
+		for _, test := range stepSnapshotTests(step) {
+			if test.File == "" {
+				continue
+			}
+			golden, err := normalizeSnapshot(stdout, test.Normalizers)
+			if err != nil {
+				return written, err
+			}
+			test.Golden = golden
+			if err := os.WriteFile(test.File, []byte(golden+"\n"), 0o644); err != nil {
+				return written, fmt.Errorf("failed to write snapshot %q: %w", test.File, err)
+			}
+			written = append(written, test.File)
+		}
+	}
+	return written, nil
+}
+
+func snapshotTests(data api.CLIData) []*api.StdoutSnapshotTest {
+	var tests []*api.StdoutSnapshotTest
+	for _, step := range data.Steps {
+		tests = append(tests, stepSnapshotTests(step)...)
+	}
+	return tests
+}
+
+func stepSnapshotTests(step api.CLIStep) []*api.StdoutSnapshotTest {
+	var tests []api.CLICommandTest
+	switch {
+	case step.CLICommand != nil:
+		tests = step.CLICommand.Tests
+	case step.Session != nil:
+		tests = step.Session.Tests
+	}
+
+	var snapshots []*api.StdoutSnapshotTest
+	for _, test := range tests {
+		if test.StdoutSnapshot != nil {
+			snapshots = append(snapshots, test.StdoutSnapshot)
+		}
+	}
+	return snapshots
+}
+
+func prettyPrintStdoutSnapshotTest(test api.StdoutSnapshotTest) string {
+	if test.File != "" {
+		return fmt.Sprintf("Expect stdout to match snapshot %s", filepath.Base(test.File))
+	}
+	return "Expect stdout to match snapshot"
+}
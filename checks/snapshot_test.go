@@ -0,0 +1,145 @@
+package checks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	api "github.com/bootdotdev/bootdev/client"
+)
+
+func TestNormalizeSnapshot(t *testing.T) {
+	stdout := "\x1b[32mok\x1b[0m   request  42\n" +
+		"at 2024-05-01T12:30:00.123Z id 0b7e8a4c-1f2d-4c3b-9a8e-6d5f4e3c2b1a\n"
+
+	got, err := normalizeSnapshot(stdout, api.SnapshotNormalizers{
+		StripANSI:          true,
+		CollapseWhitespace: true,
+		Timestamps:         true,
+		UUIDs:              true,
+		Replacements:       []api.SnapshotReplacement{{Regex: `request \d+`, Placeholder: "request <N>"}},
+	})
+	if err != nil {
+		t.Fatalf("normalizeSnapshot() error = %v", err)
+	}
+
+	want := "ok request <N>\nat <TIMESTAMP> id <UUID>"
+	if got != want {
+		t.Fatalf("normalizeSnapshot() = %q, want %q", got, want)
+	}
+}
+
+func TestEvaluateStdoutSnapshotPrintsDiff(t *testing.T) {
+	test := api.StdoutSnapshotTest{Golden: "one\ntwo\nthree\n"}
+
+	if err := evaluateStdoutSnapshot("one\ntwo\nthree", test, false); err != nil {
+		t.Fatalf("evaluateStdoutSnapshot() error = %v", err)
+	}
+
+	err := evaluateStdoutSnapshot("one\n2\nthree", test, false)
+	if err == nil {
+		t.Fatal("expected snapshot mismatch")
+	}
+	want := "expected stdout to match snapshot:\n--- expected\n+++ actual\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three"
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestUnifiedDiffSplitsDistantHunks(t *testing.T) {
+	var expected, actual []string
+	for i := range 20 {
+		line := strings.Repeat("x", i+1)
+		expected = append(expected, line)
+		actual = append(actual, line)
+	}
+	actual[1] = "changed"
+	actual[18] = "changed"
+
+	diff := unifiedDiff(strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	if strings.Count(diff, "@@ -") != 2 {
+		t.Fatalf("expected two hunks, got:\n%s", diff)
+	}
+	if !strings.Contains(diff, "@@ -1,5 +1,5 @@") || !strings.Contains(diff, "@@ -16,5 +16,5 @@") {
+		t.Fatalf("unexpected hunk headers:\n%s", diff)
+	}
+}
+
+func TestLoadSnapshotsRequiresFileUnlessUpdating(t *testing.T) {
+	data := api.CLIData{Steps: []api.CLIStep{{
+		CLICommand: &api.CLIStepCLICommand{Tests: []api.CLICommandTest{{
+			StdoutSnapshot: &api.StdoutSnapshotTest{File: "missing.golden"},
+		}}},
+	}}}
+
+	err := LoadSnapshots(&data, t.TempDir(), false)
+	if err == nil || !strings.Contains(err.Error(), "failed to read snapshot") {
+		t.Fatalf("LoadSnapshots() error = %v, want a missing snapshot error", err)
+	}
+}
+
+func TestUpdateSnapshotsLeavesInlineGoldensFailing(t *testing.T) {
+	snapshot := &api.StdoutSnapshotTest{Golden: "expected"}
+	data := api.CLIData{Steps: []api.CLIStep{{
+		CLICommand: &api.CLIStepCLICommand{Tests: []api.CLICommandTest{{StdoutSnapshot: snapshot}}},
+	}}}
+	results := []api.CLIStepResult{{CLICommandResult: &api.CLICommandResult{Stdout: "actual"}}}
+
+	written, err := UpdateSnapshots(data, results)
+	if err != nil || len(written) != 0 {
+		t.Fatalf("UpdateSnapshots() = %v, %v, want nothing written", written, err)
+	}
+	if snapshot.Golden != "expected" {
+		t.Fatalf("golden = %q, want the inline golden unchanged", snapshot.Golden)
+	}
+	failure := EvaluateCLIResults(data, results)
+	if failure == nil || !strings.Contains(failure.ErrorMessage, "expected stdout to match snapshot") {
+		t.Fatalf("failure = %#v, want the inline snapshot to still fail", failure)
+	}
+}
+
+func TestLoadAndUpdateSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := &api.StdoutSnapshotTest{
+		File:        "hello.golden",
+		Normalizers: api.SnapshotNormalizers{UUIDs: true},
+	}
+	data := api.CLIData{Steps: []api.CLIStep{{
+		CLICommand: &api.CLIStepCLICommand{Tests: []api.CLICommandTest{{StdoutSnapshot: snapshot}}},
+	}}}
+
+	if err := LoadSnapshots(&data, dir, true); err != nil {
+		t.Fatalf("LoadSnapshots() error = %v", err)
+	}
+	if snapshot.File != filepath.Join(dir, "hello.golden") || snapshot.Golden != "" {
+		t.Fatalf("unexpected snapshot after load: %#v", snapshot)
+	}
+
+	results := []api.CLIStepResult{{CLICommandResult: &api.CLICommandResult{
+		Stdout: "hello 0b7e8a4c-1f2d-4c3b-9a8e-6d5f4e3c2b1a",
+	}}}
+	written, err := UpdateSnapshots(data, results)
+	if err != nil {
+		t.Fatalf("UpdateSnapshots() error = %v", err)
+	}
+	if len(written) != 1 || written[0] != snapshot.File {
+		t.Fatalf("written = %v, want %s", written, snapshot.File)
+	}
+
+	contents, err := os.ReadFile(snapshot.File)
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+	if string(contents) != "hello <UUID>\n" {
+		t.Fatalf("snapshot contents = %q", contents)
+	}
+
+	snapshot.Golden = ""
+	if err := LoadSnapshots(&data, dir, true); err != nil {
+		t.Fatalf("LoadSnapshots() error = %v", err)
+	}
+	if err := evaluateStdoutSnapshot("hello 11111111-2222-3333-4444-555555555555", *snapshot, false); err != nil {
+		t.Fatalf("evaluateStdoutSnapshot() error = %v", err)
+	}
+}
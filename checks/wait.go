@@ -0,0 +1,181 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/bootdotdev/bootdev/messages"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	defaultWaitForTimeout  = 30 * time.Second
+	defaultWaitForInterval = 250 * time.Millisecond
+	waitForProbeTimeout    = time.Second
+
+	// how often the elapsed time is updated while waiting
+	waitForProgressInterval = 250 * time.Millisecond
+)
+
+type waitForProbe struct {
+	target string
+	check  func(timeout time.Duration) error
+}
+
+func runWaitFor(waitFor api.CLIStepWaitFor, variables map[string]string, send func(tea.Msg)) (result api.WaitForResult) {
+	result.WaitFor = waitFor
+	result.Variables = maps.Clone(variables)
+
+	probe, err := newWaitForProbe(waitFor, variables)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	result.Target = probe.target
+
+	timeout := durationFromMs(waitFor.TimeoutMs, defaultWaitForTimeout)
+	interval := durationFromMs(waitFor.IntervalMs, defaultWaitForInterval)
+	start := time.Now()
+	deadline := start.Add(timeout)
+
+	var attempts atomic.Int64
+	stopProgress := reportWaitProgress(start, timeout, &attempts, send)
+	for {
+		// a slow probe can't run past the deadline
+		probeTimeout := min(waitForProbeTimeout, time.Until(deadline))
+		if probeTimeout <= 0 {
+			result.Err = fmt.Sprintf("timed out after %s waiting for %s: %s", timeout, probe.target, err)
+			break
+		}
+		attempts.Add(1)
+		err = probe.check(probeTimeout)
+		if err == nil {
+			result.Ready = true
+			break
+		}
+		if time.Until(deadline) < interval {
+			result.Err = fmt.Sprintf("timed out after %s waiting for %s: %s", timeout, probe.target, err)
+			break
+		}
+		time.Sleep(interval)
+	}
+	stopProgress()
+
+	result.Attempts = int(attempts.Load())
+	result.ElapsedMs = int(time.Since(start).Milliseconds())
+	send(messages.WaitProgressMsg{
+		Attempt:   result.Attempts,
+		ElapsedMs: result.ElapsedMs,
+		TimeoutMs: int(timeout.Milliseconds()),
+	})
+	return result
+}
+
+// reportWaitProgress keeps the elapsed time moving during slow probes and long
+// intervals. The returned func stops it and waits until it has.
+func reportWaitProgress(start time.Time, timeout time.Duration, attempts *atomic.Int64, send func(tea.Msg)) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(waitForProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				send(messages.WaitProgressMsg{
+					Attempt:   int(attempts.Load()),
+					ElapsedMs: int(time.Since(start).Milliseconds()),
+					TimeoutMs: int(timeout.Milliseconds()),
+				})
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+func describeWaitFor(waitFor api.CLIStepWaitFor, variables map[string]string) string {
+	probe, err := newWaitForProbe(waitFor, variables)
+	if err != nil {
+		return err.Error()
+	}
+	return probe.target
+}
+
+func newWaitForProbe(waitFor api.CLIStepWaitFor, variables map[string]string) (waitForProbe, error) {
+	configured := 0
+	for _, target := range []string{waitFor.TCPAddress, waitFor.HTTPURL, waitFor.FilePath} {
+		if target != "" {
+			configured++
+		}
+	}
+	if configured != 1 {
+		return waitForProbe{}, errors.New("invalid waitFor configuration")
+	}
+
+	switch {
+	case waitFor.TCPAddress != "":
+		address := InterpolateVariables(waitFor.TCPAddress, variables)
+		return waitForProbe{
+			target: "tcp " + address,
+			check: func(timeout time.Duration) error {
+				conn, err := net.DialTimeout("tcp", address, timeout)
+				if err != nil {
+					return err
+				}
+				return conn.Close()
+			},
+		}, nil
+
+	case waitFor.HTTPURL != "":
+		url := InterpolateVariables(waitFor.HTTPURL, variables)
+		wantStatus := http.StatusOK
+		if waitFor.HTTPStatusCode != nil {
+			wantStatus = *waitFor.HTTPStatusCode
+		}
+		return waitForProbe{
+			target: fmt.Sprintf("GET %s (status %d)", url, wantStatus),
+			check: func(timeout time.Duration) error {
+				client := &http.Client{Timeout: timeout}
+				resp, err := client.Get(url)
+				if err != nil {
+					return err
+				}
+				resp.Body.Close()
+				if resp.StatusCode != wantStatus {
+					return fmt.Errorf("got status code %d", resp.StatusCode)
+				}
+				return nil
+			},
+		}, nil
+
+	default:
+		path := InterpolateVariables(waitFor.FilePath, variables)
+		return waitForProbe{
+			target: "file " + path,
+			check: func(time.Duration) error {
+				_, err := os.Stat(path)
+				return err
+			},
+		}, nil
+	}
+}
+
+func durationFromMs(ms *int, fallback time.Duration) time.Duration {
+	if ms != nil && *ms > 0 {
+		return time.Duration(*ms) * time.Millisecond
+	}
+	return fallback
+}
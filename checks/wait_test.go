@@ -0,0 +1,132 @@
+package checks
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/bootdotdev/bootdev/messages"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRunWaitForTCPAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var progress []messages.WaitProgressMsg
+	result := runWaitFor(
+		api.CLIStepWaitFor{TCPAddress: "${host}"},
+		map[string]string{"host": listener.Addr().String()},
+		func(msg tea.Msg) {
+			if msg, ok := msg.(messages.WaitProgressMsg); ok {
+				progress = append(progress, msg)
+			}
+		},
+	)
+
+	if !result.Ready || result.Err != "" {
+		t.Fatalf("result = %#v, want ready", result)
+	}
+	if len(progress) != 1 || progress[0].Attempt != 1 {
+		t.Fatalf("progress = %#v, want one attempt", progress)
+	}
+}
+
+func TestRunWaitForFileThatAppearsLater(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ready")
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = os.WriteFile(path, nil, 0o600)
+	}()
+
+	result := runWaitFor(api.CLIStepWaitFor{
+		FilePath:   path,
+		TimeoutMs:  intPtr(5000),
+		IntervalMs: intPtr(20),
+	}, map[string]string{}, func(tea.Msg) {})
+
+	if !result.Ready {
+		t.Fatalf("result = %#v, want ready", result)
+	}
+	if result.Attempts < 2 {
+		t.Fatalf("attempts = %d, want polling until the file exists", result.Attempts)
+	}
+}
+
+func TestRunWaitForHTTPTimesOutOnUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	result := runWaitFor(api.CLIStepWaitFor{
+		HTTPURL:    "${baseURL}/health",
+		TimeoutMs:  intPtr(100),
+		IntervalMs: intPtr(20),
+	}, map[string]string{"baseURL": server.URL}, func(tea.Msg) {})
+
+	if result.Ready {
+		t.Fatal("expected wait to time out")
+	}
+	if !strings.Contains(result.Err, "got status code 503") {
+		t.Fatalf("error = %q, want last probe error", result.Err)
+	}
+}
+
+func TestRunWaitForBoundsProbesByTheDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	var progress []messages.WaitProgressMsg
+	var mu sync.Mutex
+	start := time.Now()
+	result := runWaitFor(api.CLIStepWaitFor{
+		HTTPURL:   server.URL,
+		TimeoutMs: intPtr(600),
+	}, map[string]string{}, func(msg tea.Msg) {
+		if msg, ok := msg.(messages.WaitProgressMsg); ok {
+			mu.Lock()
+			progress = append(progress, msg)
+			mu.Unlock()
+		}
+	})
+
+	if result.Ready {
+		t.Fatal("expected wait to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Fatalf("wait took %s, want the probe cut off at the 600ms deadline", elapsed)
+	}
+	if len(progress) < 2 {
+		t.Fatalf("progress = %#v, want updates while the probe was running", progress)
+	}
+}
+
+func TestRunWaitForRequiresExactlyOneProbe(t *testing.T) {
+	result := runWaitFor(api.CLIStepWaitFor{
+		TCPAddress: "localhost:8080",
+		FilePath:   "ready",
+	}, map[string]string{}, func(tea.Msg) {})
+
+	if result.Err != "invalid waitFor configuration" {
+		t.Fatalf("error = %q, want invalid configuration", result.Err)
+	}
+	if result.Attempts != 0 {
+		t.Fatalf("attempts = %d, want no probes", result.Attempts)
+	}
+}
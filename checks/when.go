@@ -0,0 +1,134 @@
+package checks
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/bootdotdev/bootdev/messages"
+)
+
+// shouldRunStep checks the step's When conditions against the variables and
+// the results so far. When it returns false, reason says why.
+func shouldRunStep(
+	stepIndex int,
+	cliData api.CLIData,
+	results []api.CLIStepResult,
+	variables map[string]string,
+) (run bool, reason string) {
+	when := cliData.Steps[stepIndex].When
+	if when == nil {
+		return true, ""
+	}
+
+	if when.VariableExists != "" {
+		if _, ok := variables[when.VariableExists]; !ok {
+			return false, fmt.Sprintf("variable %s isn't set", when.VariableExists)
+		}
+	}
+
+	if when.VariableEquals != nil {
+		want := InterpolateVariables(when.VariableEquals.Value, variables)
+		got, ok := variables[when.VariableEquals.Name]
+		if !ok {
+			return false, fmt.Sprintf("variable %s isn't set", when.VariableEquals.Name)
+		}
+		if got != want {
+			return false, fmt.Sprintf("variable %s isn't %q", when.VariableEquals.Name, want)
+		}
+	}
+
+	if len(when.OS) > 0 && !slices.Contains(when.OS, runtime.GOOS) {
+		return false, fmt.Sprintf("only runs on %s", strings.Join(when.OS, ", "))
+	}
+
+	if when.PreviousStep != nil {
+		return previousStepMatches(*when.PreviousStep, stepIndex, cliData, results)
+	}
+
+	return true, ""
+}
+
+func previousStepMatches(
+	condition api.WhenPreviousStep,
+	stepIndex int,
+	cliData api.CLIData,
+	results []api.CLIStepResult,
+) (bool, string) {
+	if stepIndex == 0 {
+		return false, "there's no previous step"
+	}
+	previousIndex := stepIndex - 1
+	previous := results[previousIndex]
+	if previous.Skipped {
+		return false, "the previous step was skipped"
+	}
+
+	if condition.Passed != nil {
+		passed := evaluateStepResult(previousIndex, cliData.Steps[previousIndex], previous) == nil
+		if passed != *condition.Passed {
+			if passed {
+				return false, "the previous step passed"
+			}
+			return false, "the previous step didn't pass"
+		}
+	}
+
+	if condition.StatusCode != nil {
+		if previous.HTTPRequestResult == nil {
+			return false, "the previous step isn't an HTTP request"
+		}
+		if previous.HTTPRequestResult.StatusCode != *condition.StatusCode {
+			return false, fmt.Sprintf("the previous step returned status %d", previous.HTTPRequestResult.StatusCode)
+		}
+	}
+
+	if condition.ExitCode != nil {
+		if previous.CLICommandResult == nil {
+			return false, "the previous step isn't a CLI command"
+		}
+		if previous.CLICommandResult.ExitCode != *condition.ExitCode {
+			return false, fmt.Sprintf("the previous step exited with code %d", previous.CLICommandResult.ExitCode)
+		}
+	}
+
+	return true, ""
+}
+
+// skippedStepMsg describes a skipped step the same way its StartStepMsg would,
+// minus anything that needs the step to run.
+func skippedStepMsg(step api.CLIStep, baseURL string, variables map[string]string) messages.StartStepMsg {
+	msg := messages.StartStepMsg{
+		Description:     step.Description,
+		NoPenaltyOnFail: step.NoPenaltyOnFail,
+	}
+	switch {
+	case step.CLICommand != nil:
+		msg.CMD = step.CLICommand.CommandFor(runtime.GOOS)
+	case step.HTTPRequest != nil:
+		fullURL := strings.Replace(step.HTTPRequest.Request.FullURL, api.BaseURLPlaceholder, baseURL, 1)
+		msg.URL = InterpolateVariables(fullURL, variables)
+		msg.Method = step.HTTPRequest.Request.Method
+	case step.HTTPLoad != nil:
+		fullURL := strings.Replace(step.HTTPLoad.Request.FullURL, api.BaseURLPlaceholder, baseURL, 1)
+		msg.URL = InterpolateVariables(fullURL, variables)
+		msg.Method = step.HTTPLoad.Request.Method
+		msg.Load = describeHTTPLoad(*step.HTTPLoad)
+	case step.BackgroundProcess != nil:
+		msg.CMD = step.BackgroundProcess.Command
+		msg.Background = true
+	case step.Session != nil:
+		msg.CMD = step.Session.Command
+	case step.Filesystem != nil:
+		for _, test := range step.Filesystem.Tests {
+			msg.Paths = append(msg.Paths, InterpolateVariables(test.Path, variables))
+		}
+	case step.Parallel != nil:
+		msg.Parallel = len(parallelCopies(*step.Parallel))
+	case step.WaitFor != nil:
+		msg.WaitFor = describeWaitFor(*step.WaitFor, variables)
+	}
+	return msg
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-json"
+	"go.yaml.in/yaml/v3"
+)
+
+// CommandDefaultOS is the Commands key used when there's no entry for the
+// current OS
+const CommandDefaultOS = "default"
+
+// CommandFor returns the command variant for goos, falling back to the default
+func (c CLIStepCLICommand) CommandFor(goos string) string {
+	if command, ok := c.Commands[goos]; ok {
+		return command
+	}
+	return c.Command
+}
+
+type cliStepCLICommandFields CLIStepCLICommand
+
+func (c *CLIStepCLICommand) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value != "command" || value.Kind != yaml.MappingNode {
+				content = append(content, key, value)
+				continue
+			}
+			var commands map[string]string
+			if err := value.Decode(&commands); err != nil {
+				return fmt.Errorf("invalid command variants: %w", err)
+			}
+			c.setCommands(commands)
+		}
+		stripped := *node
+		stripped.Content = content
+		node = &stripped
+	}
+	return node.Decode((*cliStepCLICommandFields)(c))
+}
+
+func (c *CLIStepCLICommand) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		cliStepCLICommandFields
+		Command json.RawMessage
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*c = CLIStepCLICommand(fields.cliStepCLICommandFields)
+	if len(fields.Command) == 0 || string(fields.Command) == "null" {
+		return nil
+	}
+
+	var command string
+	if err := json.Unmarshal(fields.Command, &command); err == nil {
+		c.Command = command
+		return nil
+	}
+	var commands map[string]string
+	if err := json.Unmarshal(fields.Command, &commands); err != nil {
+		return fmt.Errorf("command must be a string or a map of OS to command: %w", err)
+	}
+	c.setCommands(commands)
+	return nil
+}
+
+func (c *CLIStepCLICommand) setCommands(commands map[string]string) {
+	c.Command = commands[CommandDefaultOS]
+	c.Commands = make(map[string]string, len(commands))
+	for goos, command := range commands {
+		if goos != CommandDefaultOS {
+			c.Commands[goos] = command
+		}
+	}
+}
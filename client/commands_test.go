@@ -0,0 +1,69 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestCLIStepCLICommandDecodesCommandVariants(t *testing.T) {
+	want := CLIStepCLICommand{
+		Command:   "ls -la",
+		Commands:  map[string]string{"windows": "dir"},
+		TimeoutMs: func() *int { ms := 500; return &ms }(),
+	}
+
+	var fromYAML CLIStepCLICommand
+	manifest := "command:\n  default: ls -la\n  windows: dir\ntimeoutMs: 500\n"
+	if err := yaml.Unmarshal([]byte(manifest), &fromYAML); err != nil {
+		t.Fatalf("unmarshal YAML: %v", err)
+	}
+	if !reflect.DeepEqual(fromYAML, want) {
+		t.Fatalf("YAML command = %#v, want %#v", fromYAML, want)
+	}
+
+	var fromJSON CLIStepCLICommand
+	payload := `{"Command":{"default":"ls -la","windows":"dir"},"TimeoutMs":500}`
+	if err := json.Unmarshal([]byte(payload), &fromJSON); err != nil {
+		t.Fatalf("unmarshal JSON: %v", err)
+	}
+	if !reflect.DeepEqual(fromJSON, want) {
+		t.Fatalf("JSON command = %#v, want %#v", fromJSON, want)
+	}
+
+	roundTrip, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("marshal command: %v", err)
+	}
+	var decoded CLIStepCLICommand
+	if err := json.Unmarshal(roundTrip, &decoded); err != nil {
+		t.Fatalf("unmarshal round trip: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Fatalf("round trip = %#v, want %#v", decoded, want)
+	}
+
+	if got := want.CommandFor("windows"); got != "dir" {
+		t.Fatalf("CommandFor(windows) = %q, want dir", got)
+	}
+	if got := want.CommandFor("linux"); got != "ls -la" {
+		t.Fatalf("CommandFor(linux) = %q, want the default", got)
+	}
+}
+
+func TestCLIStepCLICommandDecodesPlainCommand(t *testing.T) {
+	var fromYAML, fromJSON CLIStepCLICommand
+	if err := yaml.Unmarshal([]byte("command: echo hi\n"), &fromYAML); err != nil {
+		t.Fatalf("unmarshal YAML: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"Command":"echo hi"}`), &fromJSON); err != nil {
+		t.Fatalf("unmarshal JSON: %v", err)
+	}
+	for _, got := range []CLIStepCLICommand{fromYAML, fromJSON} {
+		if got.Command != "echo hi" || got.Commands != nil {
+			t.Fatalf("command = %#v, want a plain command", got)
+		}
+	}
+}
@@ -29,34 +29,235 @@ type CLIData struct {
 	BaseURLDefault          string    `yaml:"baseURLDefault"`
 	Steps                   []CLIStep `yaml:"steps"`
 	AllowedOperatingSystems []string  `yaml:"allowedOperatingSystems"`
+	// CommandTimeoutMs is the timeout for CLI commands and sessions that don't
+	// set their own timeoutMs
+	CommandTimeoutMs *int `yaml:"commandTimeoutMs"`
 }
 
 type CLIStep struct {
-	Description     string              `yaml:"description"`
-	CLICommand      *CLIStepCLICommand  `yaml:"cliCommand"`
-	HTTPRequest     *CLIStepHTTPRequest `yaml:"httpRequest"`
-	NoPenaltyOnFail bool                `yaml:"noPenaltyOnFail"`
+	Description       string                    `yaml:"description"`
+	CLICommand        *CLIStepCLICommand        `yaml:"cliCommand"`
+	HTTPRequest       *CLIStepHTTPRequest       `yaml:"httpRequest"`
+	HTTPLoad          *CLIStepHTTPLoad          `yaml:"httpLoad"`
+	BackgroundProcess *CLIStepBackgroundProcess `yaml:"backgroundProcess"`
+	WaitFor           *CLIStepWaitFor           `yaml:"waitFor"`
+	Session           *CLIStepSession           `yaml:"session"`
+	Filesystem        *CLIStepFilesystem        `yaml:"filesystem"`
+	Parallel          *CLIStepParallel          `yaml:"parallel"`
+	Retry             *CLIStepRetry             `yaml:"retry"`
+	Repeat            int                       `yaml:"repeat"`  // runs the step N times with ${index}
+	Foreach           string                    `yaml:"foreach"` // runs the step per item of a JSON array, with ${index} and ${item}
+	When              *CLIStepWhen              `yaml:"when"`
+	NoPenaltyOnFail   bool                      `yaml:"noPenaltyOnFail"`
 }
 
+// CLIStepWhen runs the step only if every condition that's set holds.
+// Otherwise the step is skipped, which doesn't fail the lesson.
+type CLIStepWhen struct {
+	VariableExists string              `yaml:"variableExists"`
+	VariableEquals *WhenVariableEquals `yaml:"variableEquals"`
+	OS             []string            `yaml:"os"` // GOOS values, e.g. "linux"
+	PreviousStep   *WhenPreviousStep   `yaml:"previousStep"`
+}
+
+type WhenVariableEquals struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// WhenPreviousStep checks the outcome of the step right before this one
+type WhenPreviousStep struct {
+	Passed     *bool `yaml:"passed"`
+	StatusCode *int  `yaml:"statusCode"` // HTTP request steps
+	ExitCode   *int  `yaml:"exitCode"`   // CLI command steps
+}
+
+// CLIStepRetry re-runs a CLI command or HTTP request step until its tests pass
+// or it runs out of attempts. Only the final attempt is submitted.
+type CLIStepRetry struct {
+	Attempts           int  `yaml:"attempts"`
+	DelayMs            *int `yaml:"delayMs"`
+	ExponentialBackoff bool `yaml:"exponentialBackoff"`
+}
+
+// CLIStepCLICommand's command can also be given as a map from GOOS to
+// command, with a "default" entry for every other OS. See CommandFor.
 type CLIStepCLICommand struct {
 	Command          string                     `yaml:"command"`
+	Commands         map[string]string          `yaml:"-" json:",omitempty"`
 	Tests            []CLICommandTest           `yaml:"tests"`
 	StdoutVariables  []CLICommandStdoutVariable `yaml:"stdoutVariables"`
 	SleepAfterMs     *int                       `yaml:"sleepAfterMs"`
+	TimeoutMs        *int                       `yaml:"timeoutMs"`
+	Stdin            *string                    `yaml:"stdin"`
+	StdinFile        *string                    `yaml:"stdinFile"`
 	StdoutFilterTmdl *string                    `yaml:"stdoutFilterTmdl"`
+	Normalize        *CLICommandNormalize       `yaml:"normalize"`
+	Shell            CLIShell                   `yaml:"shell"` // empty uses the platform default
 }
 
+type CLIShell string
+
+const (
+	CLIShellSh   CLIShell = "sh"
+	CLIShellBash CLIShell = "bash"
+	CLIShellZsh  CLIShell = "zsh"
+	// CLIShellExec splits the command into argv and runs it without a shell
+	CLIShellExec CLIShell = "exec"
+)
+
+// CLICommandNormalize cleans up stdout and stderr before they're tested or
+// parsed for variables, so terminal differences don't fail a lesson.
+type CLICommandNormalize struct {
+	StripANSI         bool `yaml:"stripAnsi"`
+	NormalizeNewlines bool `yaml:"normalizeNewlines"` // CRLF and lone CR become LF
+	TrimLines         bool `yaml:"trimLines"`         // trims whitespace around every line
+	CaseInsensitive   bool `yaml:"caseInsensitive"`   // text tests ignore case, but the output keeps it
+	UnicodeNFC        bool `yaml:"unicodeNFC"`
+}
+
+// CLIStepBackgroundProcess starts a command that keeps running while the
+// following steps execute. It's stopped when all steps have finished.
+type CLIStepBackgroundProcess struct {
+	Command      string `yaml:"command"`
+	SleepAfterMs *int   `yaml:"sleepAfterMs"`
+}
+
+// CLIStepWaitFor polls until exactly one of its probes succeeds or the timeout
+// passes. HTTPStatusCode defaults to 200.
+type CLIStepWaitFor struct {
+	TCPAddress     string `yaml:"tcpAddress"`
+	HTTPURL        string `yaml:"httpURL"`
+	HTTPStatusCode *int   `yaml:"httpStatusCode"`
+	FilePath       string `yaml:"filePath"`
+	TimeoutMs      *int   `yaml:"timeoutMs"`
+	IntervalMs     *int   `yaml:"intervalMs"`
+}
+
+// CLIStepSession runs a command under a pseudo-terminal and steps through its
+// actions in order. The full transcript is checked by Tests like stdout.
+type CLIStepSession struct {
+	Command      string           `yaml:"command"`
+	Actions      []SessionAction  `yaml:"actions"`
+	Tests        []CLICommandTest `yaml:"tests"`
+	TimeoutMs    *int             `yaml:"timeoutMs"`
+	SleepAfterMs *int             `yaml:"sleepAfterMs"`
+}
+
+// SessionAction should have only one of Send, Expect or ExpectRegex set.
+// Send writes a line to the session; the expect forms wait for output.
+type SessionAction struct {
+	Send        *string `yaml:"send"`
+	Expect      *string `yaml:"expect"`
+	ExpectRegex *string `yaml:"expectRegex"`
+	TimeoutMs   *int    `yaml:"timeoutMs"`
+}
+
+// CLIStepParallel starts all of its steps at the same moment and waits for
+// them to finish. Each step's own tests must pass, and Tests count across all
+// of the results.
+type CLIStepParallel struct {
+	Steps        []ParallelStep `yaml:"steps"`
+	Tests        []ParallelTest `yaml:"tests"`
+	SleepAfterMs *int           `yaml:"sleepAfterMs"`
+}
+
+// ParallelStep should have only one of CLICommand or HTTPRequest set. Count
+// starts that many identical copies and defaults to 1.
+type ParallelStep struct {
+	Count       int                 `yaml:"count"`
+	CLICommand  *CLIStepCLICommand  `yaml:"cliCommand"`
+	HTTPRequest *CLIStepHTTPRequest `yaml:"httpRequest"`
+}
+
+// ParallelTest counts the results with the given StatusCode or ExitCode and
+// compares that with Exactly, AtLeast and AtMost. With none of them set,
+// every result has to match.
+type ParallelTest struct {
+	StatusCode *int `yaml:"statusCode"`
+	ExitCode   *int `yaml:"exitCode"`
+	Exactly    *int `yaml:"exactly"`
+	AtLeast    *int `yaml:"atLeast"`
+	AtMost     *int `yaml:"atMost"`
+}
+
+type CLIStepFilesystem struct {
+	Tests        []FilesystemTest `yaml:"tests"`
+	SleepAfterMs *int             `yaml:"sleepAfterMs"`
+}
+
+// FilesystemTest should have only one check set besides Path
+type FilesystemTest struct {
+	Path            string   `yaml:"path"`
+	Exists          *bool    `yaml:"exists"`
+	ContentsContain *string  `yaml:"contentsContain"`
+	ContentsRegex   *string  `yaml:"contentsRegex"`
+	SHA256          *string  `yaml:"sha256"`
+	Mode            *string  `yaml:"mode"` // octal, e.g. "0755"
+	DirEntries      []string `yaml:"dirEntries"`
+}
+
+// CLICommandStdoutVariable sets Name from a jq Path over JSON stdout, or from a
+// Regex with one capture group. A Regex whose groups are all named sets one
+// variable per group instead, and then Name is left empty.
 type CLICommandStdoutVariable struct {
-	Name  string `yaml:"name"`
-	Regex string `yaml:"regex"`
+	Name     string `yaml:"name"`
+	Regex    string `yaml:"regex"`
+	Path     string `yaml:"path"`
+	Required bool   `yaml:"required"` // fail the step when nothing matches
+	Secret   bool   `yaml:"secret"`   // redacted from output, but still interpolated
 }
 
 type CLICommandTest struct {
-	ExitCode           *int          `yaml:"exitCode"`
-	StdoutContainsAll  []string      `yaml:"stdoutContainsAll"`
-	StdoutContainsNone []string      `yaml:"stdoutContainsNone"`
-	StdoutLinesGT      *int          `yaml:"stdoutLinesGT"`
-	StdoutJq           *StdoutJqTest `yaml:"stdoutJq"`
+	ExitCode           *int                `yaml:"exitCode"`
+	ExitCodeNonZero    *bool               `yaml:"exitCodeNonZero"`
+	ExitCodeOneOf      []int               `yaml:"exitCodeOneOf"`
+	ExitSignal         *string             `yaml:"exitSignal"` // e.g. "SIGTERM"
+	StdoutContainsAll  []string            `yaml:"stdoutContainsAll"`
+	StdoutContainsNone []string            `yaml:"stdoutContainsNone"`
+	StdoutLinesGT      *int                `yaml:"stdoutLinesGT"`
+	StdoutJq           *StdoutJqTest       `yaml:"stdoutJq"`
+	StderrContainsAll  []string            `yaml:"stderrContainsAll"`
+	StderrContainsNone []string            `yaml:"stderrContainsNone"`
+	StderrJq           *StdoutJqTest       `yaml:"stderrJq"`
+	StderrEmpty        *bool               `yaml:"stderrEmpty"`
+	StdoutSnapshot     *StdoutSnapshotTest `yaml:"stdoutSnapshot"`
+	StdoutLines        *StdoutLinesTest    `yaml:"stdoutLines"`
+	MaxDurationMs      *int                `yaml:"maxDurationMs"`
+	MaxRSSBytes        *int64              `yaml:"maxRSSBytes"`
+}
+
+// StdoutLinesTest should have only one check set. Equals and Matches compare
+// the line at Line, which counts from 1, or from the end when negative (-1 is
+// the last line).
+type StdoutLinesTest struct {
+	Line    *int     `yaml:"line"`
+	Equals  *string  `yaml:"equals"`
+	Matches *string  `yaml:"matches"` // regex
+	Count   *int     `yaml:"count"`
+	CountLT *int     `yaml:"countLT"`
+	InOrder []string `yaml:"inOrder"` // each needle must be on a later line than the one before
+}
+
+// StdoutSnapshotTest compares the whole stdout against Golden after both have
+// been normalized. When File is set, Golden is read from it instead.
+type StdoutSnapshotTest struct {
+	Golden      string              `yaml:"golden"`
+	File        string              `yaml:"file"`
+	Normalizers SnapshotNormalizers `yaml:"normalizers"`
+}
+
+type SnapshotNormalizers struct {
+	StripANSI          bool                  `yaml:"stripAnsi"`
+	CollapseWhitespace bool                  `yaml:"collapseWhitespace"`
+	Timestamps         bool                  `yaml:"timestamps"`
+	UUIDs              bool                  `yaml:"uuids"`
+	Replacements       []SnapshotReplacement `yaml:"replacements"`
+}
+
+type SnapshotReplacement struct {
+	Regex       string `yaml:"regex"`
+	Placeholder string `yaml:"placeholder"`
 }
 
 type StdoutJqTest struct {
@@ -90,6 +291,31 @@ type CLIStepHTTPRequest struct {
 	SleepAfterMs            *int                                `yaml:"sleepAfterMs"`
 }
 
+// CLIStepHTTPLoad sends Requests copies of Request, at most Concurrency at a
+// time. With RatePerSecond set, requests are also started no faster than that.
+type CLIStepHTTPLoad struct {
+	Request       HTTPRequest    `yaml:"request"`
+	Requests      int            `yaml:"requests"`
+	Concurrency   int            `yaml:"concurrency"` // defaults to 1
+	RatePerSecond float64        `yaml:"ratePerSecond"`
+	Tests         []HTTPLoadTest `yaml:"tests"`
+	SleepAfterMs  *int           `yaml:"sleepAfterMs"`
+}
+
+// HTTPLoadTest should have only one check set. StatusCode counts the responses
+// with that status and compares the count with Exactly, AtLeast and AtMost;
+// with none of them set, every request has to return it.
+type HTTPLoadTest struct {
+	P50MaxMs   *int `yaml:"p50MaxMs"`
+	P95MaxMs   *int `yaml:"p95MaxMs"`
+	P99MaxMs   *int `yaml:"p99MaxMs"`
+	MaxErrors  *int `yaml:"maxErrors"` // requests that got no response at all
+	StatusCode *int `yaml:"statusCode"`
+	Exactly    *int `yaml:"exactly"`
+	AtLeast    *int `yaml:"atLeast"`
+	AtMost     *int `yaml:"atMost"`
+}
+
 const BaseURLPlaceholder = "${baseURL}"
 
 type HTTPRequest struct {
@@ -100,9 +326,33 @@ type HTTPRequest struct {
 	BodyForm        map[string]string `yaml:"bodyForm"`
 	FollowRedirects *bool             `yaml:"followRedirects"`
 
+	// Only one body should be set. BodyRaw and BodyFile are sent with
+	// ContentType, which defaults to plain text for BodyRaw and to the file
+	// extension's type for BodyFile. A body file's contents are sent as-is.
+	BodyRaw       *string               `yaml:"bodyRaw"`
+	BodyFile      string                `yaml:"bodyFile"`
+	BodyMultipart *HTTPRequestMultipart `yaml:"bodyMultipart"`
+	ContentType   string                `yaml:"contentType"`
+
 	BasicAuth *HTTPBasicAuth `yaml:"basicAuth"`
 }
 
+// HTTPRequestMultipart is sent as multipart/form-data, with the fields sorted
+// by name and the files after them in order.
+type HTTPRequestMultipart struct {
+	Fields map[string]string          `yaml:"fields"`
+	Files  []HTTPRequestMultipartFile `yaml:"files"`
+}
+
+// HTTPRequestMultipartFile's Filename defaults to the base of Path, and its
+// ContentType to the file extension's type.
+type HTTPRequestMultipartFile struct {
+	Field       string `yaml:"field"`
+	Path        string `yaml:"path"`
+	Filename    string `yaml:"filename"`
+	ContentType string `yaml:"contentType"`
+}
+
 type HTTPBasicAuth struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
@@ -112,12 +362,14 @@ type HTTPRequestResponseVariable struct {
 	Name      string `yaml:"name"`
 	Path      string `yaml:"path"`
 	BodyRegex string `yaml:"bodyRegex"`
+	Secret    bool   `yaml:"secret"` // redacted from output, but still interpolated
 }
 
 type HTTPRequestResponseHeaderVariable struct {
 	Name   string `yaml:"name"`
 	Header string `yaml:"header"`
 	Regex  string `yaml:"regex"`
+	Secret bool   `yaml:"secret"`
 }
 
 // HTTPRequestTest should have only one field set
@@ -197,19 +449,107 @@ func FetchNextCLILesson() (*NextCLILesson, error) {
 }
 
 type CLIStepResult struct {
-	CLICommandResult  *CLICommandResult
-	HTTPRequestResult *HTTPRequestResult
+	CLICommandResult        *CLICommandResult
+	HTTPRequestResult       *HTTPRequestResult
+	BackgroundProcessResult *BackgroundProcessResult
+	WaitForResult           *WaitForResult
+	SessionResult           *SessionResult
+	FilesystemResult        *FilesystemResult
+	ParallelResult          *ParallelResult
+	HTTPLoadResult          *HTTPLoadResult
+	// Iterations holds one result per run of a repeat or foreach step, and
+	// IterationsErr is set when the foreach items couldn't be read
+	Iterations    []CLIStepResult `json:",omitempty"`
+	IterationsErr string          `json:",omitempty"`
+	// Skipped is set instead of a result when the step's When didn't hold
+	Skipped    bool   `json:",omitempty"`
+	SkipReason string `json:",omitempty"`
 }
 
+// ExitCodeInternalFailure is reported in place of an exit code when the runner
+// couldn't run the command to completion itself
+const ExitCodeInternalFailure = -2
+
+// CLICommandOutcome says how a command ended, so a runner failure is never
+// mistaken for the command's own exit code
+type CLICommandOutcome string
+
+const (
+	CLICommandExited          CLICommandOutcome = "exited"
+	CLICommandSignaled        CLICommandOutcome = "signaled"
+	CLICommandTimedOut        CLICommandOutcome = "timedOut"
+	CLICommandInternalFailure CLICommandOutcome = "internalFailure"
+)
+
 type CLICommandResult struct {
 	ExitCode     int
+	Outcome      CLICommandOutcome `json:",omitempty"`
+	Signal       string            `json:",omitempty"` // e.g. "SIGKILL", set when Outcome is CLICommandSignaled
+	DurationMs   int               `json:",omitempty"`
+	UserCPUMs    int               `json:",omitempty"`
+	MaxRSSBytes  int64             `json:",omitempty"` // 0 where the platform doesn't report it
 	Err          string            `json:"-"`
 	FinalCommand string            `json:"-"`
 	Command      CLIStepCLICommand `json:"-"`
 	Stdout       string
 	Stderr       string `json:"-"`
+	// SubmittedStderr mirrors Stderr only when the lesson has stderr tests,
+	// so diagnostics aren't sent to the server unless they're being checked
+	SubmittedStderr string `json:"Stderr,omitempty"`
+	Variables       map[string]string
+	JqOutputs       []CLICommandJqOutput `json:"-"`
+}
+
+type BackgroundProcessResult struct {
+	Err          string                   `json:",omitempty"`
+	ExitedEarly  bool                     `json:",omitempty"`
+	ExitCode     int                      `json:",omitempty"`
+	FinalCommand string                   `json:"-"`
+	Command      CLIStepBackgroundProcess `json:"-"`
+	Stdout       string                   `json:"-"`
+	Stderr       string                   `json:"-"`
 	Variables    map[string]string
-	JqOutputs    []CLICommandJqOutput `json:"-"`
+}
+
+type WaitForResult struct {
+	Err       string `json:",omitempty"`
+	Ready     bool
+	Attempts  int
+	ElapsedMs int
+	Target    string         `json:"-"`
+	WaitFor   CLIStepWaitFor `json:"-"`
+	Variables map[string]string
+}
+
+type SessionResult struct {
+	Err              string `json:",omitempty"`
+	ActionsCompleted int
+	ExitCode         int
+	Outcome          CLICommandOutcome `json:",omitempty"`
+	Signal           string            `json:",omitempty"` // set when Outcome is CLICommandSignaled
+	Stdout           string
+	FinalCommand     string         `json:"-"`
+	Session          CLIStepSession `json:"-"`
+	Variables        map[string]string
+}
+
+type FilesystemResult struct {
+	Entries    []FilesystemEntry
+	Filesystem CLIStepFilesystem `json:"-"`
+	Variables  map[string]string
+}
+
+// FilesystemEntry describes the path checked by the test at the same index.
+// Directory entries end in a slash when they're directories themselves.
+type FilesystemEntry struct {
+	Path       string
+	Exists     bool
+	IsDir      bool     `json:",omitempty"`
+	Mode       string   `json:",omitempty"`
+	SHA256     string   `json:",omitempty"`
+	Contents   string   `json:",omitempty"`
+	DirEntries []string `json:",omitempty"`
+	Err        string   `json:",omitempty"`
 }
 
 type CLICommandJqOutput struct {
@@ -218,9 +558,39 @@ type CLICommandJqOutput struct {
 	Error   string
 }
 
+// HTTPLoadResult aggregates a load test. Latencies only count requests that
+// got a response, and Histogram splits them into equal-width buckets.
+type HTTPLoadResult struct {
+	Err         string `json:",omitempty"`
+	Requests    int
+	Errors      int
+	StatusCodes map[int]int
+	P50Ms       float64
+	P95Ms       float64
+	P99Ms       float64
+	MaxMs       float64
+	DurationMs  int
+	Histogram   []HTTPLoadBucket
+	Variables   map[string]string
+	Load        CLIStepHTTPLoad `json:"-"`
+}
+
+type HTTPLoadBucket struct {
+	UpToMs float64
+	Count  int
+}
+
+// ParallelResult has one result per started copy, in the order of the steps
+type ParallelResult struct {
+	Results []CLIStepResult
+}
+
 type HTTPRequestResult struct {
-	Err              string `json:"FetchErr,omitempty"`
-	StatusCode       int
+	Err        string `json:"FetchErr,omitempty"`
+	StatusCode int
+	// RequestBody is the body that was sent, or a summary of it when it's
+	// binary or multipart. It's only used for local output.
+	RequestBody      string `json:"-"`
 	ResponseHeaders  map[string]string
 	ResponseTrailers map[string]string
 	BodyString       string
@@ -57,3 +57,23 @@ func TestHTTPRequestResultOmitsEmptyFetchError(t *testing.T) {
 		t.Fatalf("submission payload unexpectedly contains an empty FetchErr: %s", payload)
 	}
 }
+
+func TestHTTPLoadResultOmitsRequestDefinitionFromJSON(t *testing.T) {
+	payload, err := json.Marshal(CLIStepResult{HTTPLoadResult: &HTTPLoadResult{
+		Requests: 1,
+		Load: CLIStepHTTPLoad{Request: HTTPRequest{
+			FullURL:   "http://localhost/private",
+			Headers:   map[string]string{"X-Api-Key": "key-123"},
+			BasicAuth: &HTTPBasicAuth{Username: "boots", Password: "hunter2"},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("marshal HTTP load result: %v", err)
+	}
+
+	for _, leaked := range []string{"private", "key-123", "hunter2"} {
+		if strings.Contains(string(payload), leaked) {
+			t.Fatalf("submission payload unexpectedly contains the request definition: %s", payload)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/bootdotdev/bootdev/checks"
+	api "github.com/bootdotdev/bootdev/client"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -140,12 +142,62 @@ var configureBaseURLCmd = &cobra.Command{
 	},
 }
 
+// configureShellCmd represents the `configure shell` command
+var configureShellCmd = &cobra.Command{
+	Use:   "shell [sh|bash|zsh|exec]",
+	Short: "Get or set the shell for CLI commands that don't choose one",
+	Long: `Get or set the shell for CLI command steps that don't choose one, including
+the commands in parallel steps. Background processes and interactive
+sessions always run through the platform default shell.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resetOverrideShell, err := cmd.Flags().GetBool("reset")
+		if err != nil {
+			return fmt.Errorf("couldn't get the reset flag value: %v", err)
+		}
+
+		if resetOverrideShell {
+			viper.Set("override_shell", "")
+			if err := viper.WriteConfig(); err != nil {
+				return fmt.Errorf("failed to write config: %v", err)
+			}
+			fmt.Println("Shell reset!")
+			return nil
+		}
+
+		if len(args) == 0 {
+			shell := viper.GetString("override_shell")
+			message := fmt.Sprintf("Shell: %s", shell)
+			if shell == "" {
+				message = "No shell set"
+			}
+			fmt.Println(message)
+			return nil
+		}
+
+		shell := api.CLIShell(args[0])
+		if err := checks.ValidateShell(shell); err != nil {
+			return err
+		}
+
+		viper.Set("override_shell", string(shell))
+		if err := viper.WriteConfig(); err != nil {
+			return fmt.Errorf("failed to write config: %v", err)
+		}
+		fmt.Printf("Shell set to %v\n", shell)
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(configureCmd)
 
 	configureCmd.AddCommand(configureBaseURLCmd)
 	configureBaseURLCmd.Flags().Bool("reset", false, "reset the base URL to use the lesson's defaults")
 
+	configureCmd.AddCommand(configureShellCmd)
+	configureShellCmd.Flags().Bool("reset", false, "reset the shell to the platform default")
+
 	configureCmd.AddCommand(configureColorsCmd)
 	configureColorsCmd.Flags().Bool("reset", false, "reset colors to their default values")
 	for color, defaultVal := range defaultColors {
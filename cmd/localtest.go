@@ -19,8 +19,12 @@ import (
 func init() {
 	rootCmd.AddCommand(localTestCmd)
 	localTestCmd.Flags().BoolVarP(&verboseOutput, "verbose", "v", false, "show detailed final output for every step")
+	addSeedFlag(localTestCmd)
+	localTestCmd.Flags().BoolVar(&updateSnapshots, "update-snapshots", false, "rewrite stdout snapshot files with the current output (inline goldens aren't changed)")
 }
 
+var updateSnapshots bool
+
 var localTestCmd = &cobra.Command{
 	Use:    "local-test PATH",
 	Args:   cobra.ExactArgs(1),
@@ -44,19 +48,32 @@ func localTestHandler(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Using overridden base_url: %v\n", overrideBaseURL)
 		fmt.Printf("You can reset to the default with `bootdev config base_url --reset`\n\n")
 	}
+	if err := applyOverrideShell(data); err != nil {
+		return err
+	}
 
 	send, finish := render.StartRenderer(true, verboseOutput)
 	submissionEvent := api.LessonSubmissionEvent{}
+	var writtenSnapshots []string
 	defer func() {
 		finish(submissionEvent)
+		for _, path := range writtenSnapshots {
+			fmt.Fprintf(os.Stderr, "Snapshot written to %s\n", path)
+		}
 	}()
 
-	cliResults, err := checks.CLIChecks(data, overrideBaseURL, send)
+	cliResults, err := checks.CLIChecks(data, overrideBaseURL, runSeed(cmd), send)
 	if err != nil {
 		return err
 	}
+	if updateSnapshots {
+		writtenSnapshots, err = checks.UpdateSnapshots(data, cliResults)
+		if err != nil {
+			return err
+		}
+	}
 	submissionEvent = checks.LocalSubmissionEvent(data, cliResults)
-	checks.ApplySubmissionResults(data, submissionEvent.StructuredErrCLI, send)
+	checks.ApplySubmissionResults(data, cliResults, submissionEvent.StructuredErrCLI, send)
 
 	if submissionEvent.ResultSlug != api.VerificationResultSlugSuccess {
 		return localTestFailureError(submissionEvent.StructuredErrCLI)
@@ -99,10 +116,23 @@ func readLocalCLIData(path string) (api.CLIData, error) {
 	if len(data.Steps) == 0 {
 		return api.CLIData{}, errors.New("test manifest should include at least one step")
 	}
+	if err := checks.LoadSnapshots(&data, filepath.Dir(cleanPath), updateSnapshots); err != nil {
+		return api.CLIData{}, err
+	}
 
 	return data, nil
 }
 
+func applyOverrideShell(data api.CLIData) error {
+	overrideShell := viper.GetString("override_shell")
+	if overrideShell == "" {
+		return nil
+	}
+	fmt.Printf("Using overridden shell: %v\n", overrideShell)
+	fmt.Printf("You can reset to the default with `bootdev config shell --reset`\n\n")
+	return checks.ApplyShellOverride(data, api.CLIShell(overrideShell))
+}
+
 func validateAllowedOS(data api.CLIData) error {
 	if len(data.AllowedOperatingSystems) == 0 {
 		return errors.New("lesson does not specify any allowed operating systems")
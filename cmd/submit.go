@@ -20,12 +20,26 @@ var (
 	forceSubmit     bool
 	debugSubmission bool
 	verboseOutput   bool
+	variablesSeed   int64
 )
 
 func init() {
 	rootCmd.AddCommand(submitCmd)
 	submitCmd.Flags().BoolVar(&debugSubmission, "debug", false, "log submission request/response debug output")
 	submitCmd.Flags().BoolVarP(&verboseOutput, "verbose", "v", false, "show detailed final output for every step")
+	addSeedFlag(submitCmd)
+}
+
+func addSeedFlag(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&variablesSeed, "seed", 0, "seed the ${random.*} and ${run.uuid} variables so reruns get the same values")
+}
+
+// runSeed returns the --seed value, or a fresh seed when the flag isn't set.
+func runSeed(cmd *cobra.Command) int64 {
+	if cmd.Flags().Changed("seed") {
+		return variablesSeed
+	}
+	return time.Now().UnixNano()
 }
 
 // submitCmd represents the submit command
@@ -94,12 +108,19 @@ func submissionHandler(cmd *cobra.Command, args []string) error {
 	if err := validateAllowedOS(data); err != nil {
 		return err
 	}
+	// snapshot files in fetched lessons are relative to the working directory
+	if err := checks.LoadSnapshots(&data, ".", false); err != nil {
+		return err
+	}
 
 	overrideBaseURL := viper.GetString("override_base_url")
 	if overrideBaseURL != "" {
 		fmt.Printf("Using overridden base_url: %v\n", overrideBaseURL)
 		fmt.Printf("You can reset to the default with `bootdev config base_url --reset`\n\n")
 	}
+	if err := applyOverrideShell(data); err != nil {
+		return err
+	}
 
 	send, finish := render.StartRenderer(isSubmit, verboseOutput)
 	finalEvent := api.LessonSubmissionEvent{}
@@ -112,7 +133,7 @@ func submissionHandler(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	cliResults, err := checks.CLIChecks(data, overrideBaseURL, send)
+	cliResults, err := checks.CLIChecks(data, overrideBaseURL, runSeed(cmd), send)
 	if err != nil {
 		return err
 	}
@@ -120,13 +141,13 @@ func submissionHandler(cmd *cobra.Command, args []string) error {
 	if isSubmit {
 		submissionEvent, debugData, err := api.SubmitCLILesson(lessonUUID, cliResults, debugSubmission)
 		if debugSubmission {
-			debugPath, debugWriteErr = writeSubmissionDebugFile(lessonUUID, debugData)
+			debugPath, debugWriteErr = writeSubmissionDebugFile(lessonUUID, redactDebugData(debugData, data, cliResults))
 		}
 		if err != nil {
 			return err
 		}
 		finalEvent = submissionEvent
-		if err := applySubmissionEvent(data, submissionEvent, send); err != nil {
+		if err := applySubmissionEvent(data, cliResults, submissionEvent, send); err != nil {
 			return err
 		}
 	}
@@ -134,11 +155,11 @@ func submissionHandler(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func applySubmissionEvent(data api.CLIData, event api.LessonSubmissionEvent, send func(tea.Msg)) error {
+func applySubmissionEvent(data api.CLIData, results []api.CLIStepResult, event api.LessonSubmissionEvent, send func(tea.Msg)) error {
 	if event.ResultSlug == api.VerificationResultSlugSystemError {
 		return errors.New("lesson verification failed due to a system error; please try again")
 	}
-	checks.ApplySubmissionResults(data, event.StructuredErrCLI, send)
+	checks.ApplySubmissionResults(data, results, event.StructuredErrCLI, send)
 	return nil
 }
 
@@ -150,6 +171,19 @@ func reportDebugFileWrite(path string, err error) {
 	fmt.Fprintf(os.Stderr, "Submission debug output written to %s\n", path)
 }
 
+// redactDebugData hides secret variable values, since debug files are meant to
+// be shared when asking for help.
+func redactDebugData(debugData api.SubmissionDebugData, data api.CLIData, results []api.CLIStepResult) api.SubmissionDebugData {
+	variableSets := make([]map[string]string, 0, len(results))
+	for _, result := range results {
+		variableSets = append(variableSets, checks.StepResultVariableSets(result)...)
+	}
+	secrets := checks.SecretValues(checks.SecretVariableNames(data), variableSets...)
+	debugData.RequestBody = checks.RedactSecrets(debugData.RequestBody, secrets)
+	debugData.ResponseBody = checks.RedactSecrets(debugData.ResponseBody, secrets)
+	return debugData
+}
+
 func writeSubmissionDebugFile(lessonUUID string, data api.SubmissionDebugData) (string, error) {
 	now := time.Now()
 	timestamp := now.Format("20060102-150405")
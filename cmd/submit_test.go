@@ -14,7 +14,7 @@ func TestApplySubmissionEventRejectsSystemErrorWithoutMarkingStepsPassed(t *test
 	}}}
 	var sent []tea.Msg
 
-	err := applySubmissionEvent(data, api.LessonSubmissionEvent{
+	err := applySubmissionEvent(data, nil, api.LessonSubmissionEvent{
 		ResultSlug: api.VerificationResultSlugSystemError,
 	}, func(msg tea.Msg) {
 		sent = append(sent, msg)
@@ -26,3 +26,26 @@ func TestApplySubmissionEventRejectsSystemErrorWithoutMarkingStepsPassed(t *test
 		t.Fatalf("system error unexpectedly emitted result messages: %#v", sent)
 	}
 }
+
+func TestRedactDebugDataHidesSecretVariables(t *testing.T) {
+	data := api.CLIData{Steps: []api.CLIStep{{
+		HTTPRequest: &api.CLIStepHTTPRequest{
+			ResponseVariables: []api.HTTPRequestResponseVariable{{Name: "jwt", Path: ".token", Secret: true}},
+		},
+	}}}
+	results := []api.CLIStepResult{{HTTPRequestResult: &api.HTTPRequestResult{
+		Variables: map[string]string{"jwt": "eyJ.secret.sig", "id": "42"},
+	}}}
+
+	got := redactDebugData(api.SubmissionDebugData{
+		RequestBody:  `{"Variables":{"id":"42","jwt":"eyJ.secret.sig"}}`,
+		ResponseBody: "echo eyJ.secret.sig",
+	}, data, results)
+
+	if strings.Contains(got.RequestBody+got.ResponseBody, "eyJ.secret.sig") {
+		t.Fatalf("debug data leaks secret: %#v", got)
+	}
+	if !strings.Contains(got.RequestBody, `"id":"42"`) {
+		t.Fatalf("debug data redacted a non-secret variable: %s", got.RequestBody)
+	}
+}
@@ -7,7 +7,13 @@ type StartStepMsg struct {
 	CMD             string
 	URL             string
 	Method          string
+	WaitFor         string
+	Paths           []string
+	Parallel        int
+	Load            string
+	Loop            string
 	TmdlQuery       *string
+	Background      bool
 	NoPenaltyOnFail bool
 }
 
@@ -34,6 +40,29 @@ type ResolveStepMsg struct {
 	Result *api.CLIStepResult
 }
 
+type SkipStepMsg struct {
+	Index  int
+	Reason string
+}
+
 type SleepMsg struct {
 	DurationMs int
 }
+
+type RetryStepMsg struct {
+	Index       int
+	Attempt     int
+	MaxAttempts int
+	Error       string
+	DelayMs     int
+}
+
+type WaitProgressMsg struct {
+	Attempt   int
+	ElapsedMs int
+	TimeoutMs int
+}
+
+type SecretVariablesMsg struct {
+	Names []string
+}
@@ -16,6 +16,11 @@ func printHTTPRequestResult(result api.HTTPRequestResult) string {
 	}
 
 	var str strings.Builder
+	if result.RequestBody != "" {
+		str.WriteString("  Request Body: \n")
+		str.WriteString(truncateVisualOutput(result.RequestBody))
+		str.WriteByte('\n')
+	}
 	fmt.Fprintf(&str, "  Response Status Code: %v\n", result.StatusCode)
 
 	filteredHeaders := make(map[string]string)
@@ -19,7 +19,11 @@ type stepModel struct {
 	finished        bool
 	tests           []testModel
 	sleepAfter      string
+	progress        string
+	retries         []string
 	noPenaltyOnFail bool
+	skipped         bool
+	skipReason      string
 }
 
 type rootModel struct {
@@ -33,6 +37,7 @@ type rootModel struct {
 	verbose     bool
 	finalized   bool
 	clear       bool
+	secretNames []string
 }
 
 func initModel(isSubmit bool, verbose bool) rootModel {
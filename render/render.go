@@ -43,14 +43,37 @@ func (m rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case messages.StartStepMsg:
 		description := strings.TrimSpace(msg.Description)
 		detail := fmt.Sprintf("Command: %s", msg.CMD)
+		if msg.Background {
+			detail = fmt.Sprintf("Background command: %s", msg.CMD)
+		}
 		if msg.TmdlQuery != nil {
 			detail += fmt.Sprintf(" (TMDL query: '%s')", *msg.TmdlQuery)
 		}
 		if msg.CMD == "" {
 			detail = fmt.Sprintf("Request: %s %s", msg.Method, msg.URL)
 		}
+		if msg.Load != "" {
+			detail = fmt.Sprintf("Load test: %s %s (%s)", msg.Method, msg.URL, msg.Load)
+		}
+		if msg.WaitFor != "" {
+			detail = fmt.Sprintf("Wait for: %s", msg.WaitFor)
+		}
+		if len(msg.Paths) > 0 {
+			detail = fmt.Sprintf("Files: %s", strings.Join(msg.Paths, ", "))
+		}
+		if msg.Parallel > 0 {
+			detail = fmt.Sprintf("Parallel: %d steps at once", msg.Parallel)
+		}
+		if msg.Loop != "" {
+			detail += fmt.Sprintf(" (%s)", msg.Loop)
+		}
 		if description == "" {
-			description = strings.TrimPrefix(detail, "Command: ")
+			description = strings.TrimPrefix(detail, "Files: ")
+			description = strings.TrimPrefix(description, "Parallel: ")
+			description = strings.TrimPrefix(description, "Load test: ")
+			description = strings.TrimPrefix(description, "Wait for: ")
+			description = strings.TrimPrefix(description, "Background command: ")
+			description = strings.TrimPrefix(description, "Command: ")
 			description = strings.TrimPrefix(description, "Request: ")
 		}
 		m.steps = append(m.steps, stepModel{
@@ -61,6 +84,10 @@ func (m rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		})
 		return m, nil
 
+	case messages.SecretVariablesMsg:
+		m.secretNames = msg.Names
+		return m, nil
+
 	case messages.SleepMsg:
 		if len(m.steps) > 0 {
 			lastStepIdx := len(m.steps) - 1
@@ -75,7 +102,33 @@ func (m rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case messages.WaitProgressMsg:
+		if len(m.steps) > 0 {
+			lastStepIdx := len(m.steps) - 1
+			elapsedSec := float64(msg.ElapsedMs) / 1000.0
+			timeoutSec := float64(msg.TimeoutMs) / 1000.0
+			m.steps[lastStepIdx].progress = fmt.Sprintf("attempt %d, %.1fs/%.1fs", msg.Attempt, elapsedSec, timeoutSec)
+		}
+		return m, nil
+
+	case messages.RetryStepMsg:
+		m.steps[msg.Index].retries = append(
+			m.steps[msg.Index].retries,
+			fmt.Sprintf("Attempt %d/%d failed: %s", msg.Attempt, msg.MaxAttempts, msg.Error),
+		)
+		m.steps[msg.Index].progress = fmt.Sprintf("attempt %d/%d", msg.Attempt+1, msg.MaxAttempts)
+		return m, nil
+
+	case messages.SkipStepMsg:
+		m.steps[msg.Index].skipped = true
+		m.steps[msg.Index].skipReason = msg.Reason
+		m.steps[msg.Index].finished = true
+		return m, nil
+
 	case messages.ResolveStepMsg:
+		if m.steps[msg.Index].skipped {
+			return m, nil
+		}
 		m.steps[msg.Index].passed = msg.Passed
 		m.steps[msg.Index].finished = true
 		if msg.Result != nil {
@@ -91,6 +144,10 @@ func (m rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case messages.ResolveTestMsg:
+		// skipped steps, and loops whose items couldn't be read, never started any tests
+		if msg.TestIndex >= len(m.steps[msg.StepIndex].tests) {
+			return m, nil
+		}
 		m.steps[msg.StepIndex].tests[msg.TestIndex].passed = msg.Passed
 		m.steps[msg.StepIndex].tests[msg.TestIndex].finished = true
 		return m, nil
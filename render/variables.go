@@ -119,6 +119,20 @@ func availableVariablesForHTTPResult(result api.HTTPRequestResult) (entries []va
 	for key, value := range result.Request.Request.BodyForm {
 		addInterpolationNames(value, fmt.Sprintf("Request Form Field %q", key))
 	}
+	if result.Request.Request.BodyRaw != nil {
+		addInterpolationNames(*result.Request.Request.BodyRaw, "Request Raw Body")
+	}
+	addInterpolationNames(result.Request.Request.BodyFile, "Request Body File")
+	addInterpolationNames(result.Request.Request.ContentType, "Request Content Type")
+	if multipart := result.Request.Request.BodyMultipart; multipart != nil {
+		for key, value := range multipart.Fields {
+			addInterpolationNames(value, fmt.Sprintf("Request Multipart Field %q", key))
+		}
+		for _, file := range multipart.Files {
+			addInterpolationNames(file.Path, fmt.Sprintf("Request Multipart File %q", file.Field))
+			addInterpolationNames(file.Filename, fmt.Sprintf("Request Multipart File %q", file.Field))
+		}
+	}
 	if result.Request.Request.BodyJSON != nil {
 		if body, err := json.Marshal(result.Request.Request.BodyJSON); err == nil {
 			addInterpolationNames(string(body), "Request JSON Body")
@@ -173,6 +187,12 @@ func availableVariablesForCLIResult(result api.CLICommandResult) (entries []vari
 	}
 
 	addInterpolationNames(result.Command.Command, "Command")
+	if result.Command.Stdin != nil {
+		addInterpolationNames(*result.Command.Stdin, "Stdin")
+	}
+	if result.Command.StdinFile != nil {
+		addInterpolationNames(*result.Command.StdinFile, "Stdin File")
+	}
 	for _, test := range result.Command.Tests {
 		for _, contains := range test.StdoutContainsAll {
 			addInterpolationNames(contains, "Stdout Contains Test")
@@ -191,6 +211,34 @@ func availableVariablesForCLIResult(result api.CLICommandResult) (entries []vari
 				}
 			}
 		}
+		if test.StdoutLines != nil {
+			if test.StdoutLines.Equals != nil {
+				addInterpolationNames(*test.StdoutLines.Equals, "Stdout Line Test")
+			}
+			if test.StdoutLines.Matches != nil {
+				addInterpolationNames(*test.StdoutLines.Matches, "Stdout Line Test")
+			}
+			for _, needle := range test.StdoutLines.InOrder {
+				addInterpolationNames(needle, "Stdout Line Order Test")
+			}
+		}
+		for _, contains := range test.StderrContainsAll {
+			addInterpolationNames(contains, "Stderr Contains Test")
+		}
+		for _, contains := range test.StderrContainsNone {
+			addInterpolationNames(contains, "Stderr Excludes Test")
+		}
+		if test.StderrJq != nil {
+			addInterpolationNames(test.StderrJq.Query, "Stderr JQ Query")
+			for _, expected := range test.StderrJq.ExpectedResults {
+				if expected.Type != api.JqTypeString {
+					continue
+				}
+				if value, ok := expected.Value.(string); ok {
+					addInterpolationNames(value, "Stderr JQ Expected Value")
+				}
+			}
+		}
 	}
 
 	return entries, expectsVariables
@@ -127,6 +127,7 @@ func TestCLIAvailableVariables(t *testing.T) {
 			Command: "curl ${url} ${empty}",
 			Tests: []api.CLICommandTest{
 				{StdoutContainsAll: []string{"${expected}"}},
+				{StderrContainsNone: []string{"${url}"}},
 			},
 		},
 	}
@@ -146,4 +147,30 @@ func TestCLIAvailableVariables(t *testing.T) {
 	if !strings.Contains(got, "expected: [not found] (Stdout Contains Test)") {
 		t.Fatalf("expected missing expected entry in:\n%s", got)
 	}
+	if !strings.Contains(got, "url: http://localhost:42069 (Stderr Excludes Test)") {
+		t.Fatalf("expected stderr test entry in:\n%s", got)
+	}
+}
+
+func TestCLIAvailableVariablesIncludesBuiltins(t *testing.T) {
+	result := api.CLICommandResult{
+		Variables: map[string]string{
+			"run.uuid":        "0b7e8a4c-1f2d-4c3b-9a8e-6d5f4e3c2b1a",
+			"random.string:8": "k3j9x0qa",
+		},
+		Command: api.CLIStepCLICommand{
+			Command: "signup --id ${run.uuid} --name ${random.string:8|upper}",
+		},
+	}
+
+	available, _ := availableVariablesForCLIResult(result)
+	got := renderVariableSection("Variables Available", available)
+	for _, want := range []string{
+		"run.uuid: 0b7e8a4c-1f2d-4c3b-9a8e-6d5f4e3c2b1a (Command)",
+		"random.string:8: k3j9x0qa (Command)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in:\n%s", want, got)
+		}
+	}
 }
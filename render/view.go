@@ -2,10 +2,14 @@ package render
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/bootdotdev/bootdev/checks"
 	api "github.com/bootdotdev/bootdev/client"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/lipgloss"
@@ -62,6 +66,14 @@ func renderTest(text string, spinner string, isFinished bool, showStatus bool, p
 	return testStr
 }
 
+func renderRetries(retries []string) string {
+	var str strings.Builder
+	for _, retry := range retries {
+		fmt.Fprintf(&str, " > %s\n", gray.Render(retry))
+	}
+	return str.String()
+}
+
 func renderJqOutputs(outputs []api.CLICommandJqOutput) string {
 	if len(outputs) == 0 {
 		return ""
@@ -99,6 +111,23 @@ func (m rootModel) View() string {
 	if m.clear {
 		return ""
 	}
+	return checks.RedactSecrets(m.view(), m.secretValues())
+}
+
+func (m rootModel) secretValues() []string {
+	if len(m.secretNames) == 0 {
+		return nil
+	}
+	variableSets := make([]map[string]string, 0, len(m.steps))
+	for _, step := range m.steps {
+		if step.result != nil {
+			variableSets = append(variableSets, checks.StepResultVariableSets(*step.result)...)
+		}
+	}
+	return checks.SecretValues(m.secretNames, variableSets...)
+}
+
+func (m rootModel) view() string {
 	s := m.spinner.View()
 	var str strings.Builder
 	failedStepIndex := -1
@@ -110,16 +139,23 @@ func (m rootModel) View() string {
 			break
 		}
 
+		if step.skipped {
+			str.WriteString(renderSkippedStep(step))
+			continue
+		}
+
 		showAllDetails := m.finalized && (m.verbose || !m.isSubmit)
 		failed := step.passed != nil && !*step.passed
 		if showAllDetails {
 			str.WriteString(renderTestHeader(step.description, m.spinner, step.finished, m.isSubmit, step.passed, step.noPenaltyOnFail))
 			fmt.Fprintf(&str, " > %s\n", step.detail)
+			str.WriteString(renderRetries(step.retries))
 			str.WriteString(renderTests(step.tests, s))
 		} else {
 			str.WriteString(renderCompactStep(step, s, m.isSubmit))
 			if failed && m.finalized {
 				fmt.Fprintf(&str, "\n > %s\n", step.detail)
+				str.WriteString(renderRetries(step.retries))
 				str.WriteString(renderTests(step.tests, s))
 			}
 		}
@@ -212,25 +248,65 @@ func (m rootModel) View() string {
 }
 
 func renderCompactStep(step stepModel, spinner string, isSubmit bool) string {
-	line := renderTest(step.description, spinner, step.finished, isSubmit, step.passed)
+	description := step.description
+	if !step.finished && step.progress != "" {
+		description = fmt.Sprintf("%s %s", description, gray.Render(fmt.Sprintf("(%s)", step.progress)))
+	}
+	line := renderTest(description, spinner, step.finished, isSubmit, step.passed)
 	if step.noPenaltyOnFail {
 		line = fmt.Sprintf("%s %s", line, white.Render(safeStepIcon))
 	}
 	return line + "\n"
 }
 
+func renderSkippedStep(step stepModel) string {
+	return gray.Render(fmt.Sprintf("-  %s (skipped: %s)", step.description, step.skipReason)) + "\n"
+}
+
+func renderCommandOutcome(result api.CLICommandResult) string {
+	switch result.Outcome {
+	case api.CLICommandTimedOut:
+		return fmt.Sprintf("\n > Command timed out after %s\n", checks.CLICommandTimeout(result.Command))
+	case api.CLICommandSignaled:
+		return fmt.Sprintf("\n > Command killed by signal %s\n", result.Signal)
+	case api.CLICommandInternalFailure:
+		return "\n > Command failed to run\n"
+	default:
+		return fmt.Sprintf("\n > Command exit code: %d\n", result.ExitCode)
+	}
+}
+
+func renderCommandUsage(result api.CLICommandResult) string {
+	usage := fmt.Sprintf("Duration: %dms, user CPU: %dms", result.DurationMs, result.UserCPUMs)
+	if result.MaxRSSBytes > 0 {
+		usage += ", max RSS: " + checks.FormatBytes(result.MaxRSSBytes)
+	}
+	return fmt.Sprintf(" > %s\n", usage)
+}
+
 func renderStepResult(step stepModel) string {
+	if step.result.IterationsErr != "" || len(step.result.Iterations) > 0 {
+		return renderIterationsResult(step)
+	}
+
 	var str strings.Builder
 	if step.result.CLICommandResult != nil {
-		for _, test := range step.tests {
-			if strings.Contains(strings.ToLower(test.text), "exit code") {
-				fmt.Fprintf(&str, "\n > Command exit code: %d\n", step.result.CLICommandResult.ExitCode)
-				break
+		if step.result.CLICommandResult.Outcome == api.CLICommandTimedOut {
+			str.WriteString(renderCommandOutcome(*step.result.CLICommandResult))
+		} else {
+			for _, test := range step.tests {
+				text := strings.ToLower(test.text)
+				if strings.Contains(text, "exit code") || strings.Contains(text, "signal") {
+					str.WriteString(renderCommandOutcome(*step.result.CLICommandResult))
+					break
+				}
 			}
 		}
+		str.WriteString(renderCommandUsage(*step.result.CLICommandResult))
 		str.WriteString(" > Command stdout:\n\n")
 		str.WriteString(gray.Render(truncateVisualOutput(step.result.CLICommandResult.Stdout)))
 		str.WriteByte('\n')
+		str.WriteString(renderFailingStdoutLine(step, *step.result.CLICommandResult))
 		if step.result.CLICommandResult.Stderr != "" {
 			str.WriteString(" > Command stderr:\n\n")
 			str.WriteString(gray.Render(truncateVisualOutput(step.result.CLICommandResult.Stderr)))
@@ -246,9 +322,240 @@ func renderStepResult(step stepModel) string {
 	if step.result.HTTPRequestResult != nil {
 		str.WriteString(printHTTPRequestResult(*step.result.HTTPRequestResult))
 	}
+
+	if step.result.SessionResult != nil {
+		str.WriteString(printSessionResult(*step.result.SessionResult))
+	}
+
+	if step.result.FilesystemResult != nil {
+		str.WriteString(printFilesystemResult(*step.result.FilesystemResult))
+	}
+
+	if step.result.BackgroundProcessResult != nil {
+		str.WriteString(printBackgroundProcessResult(*step.result.BackgroundProcessResult))
+	}
+
+	if step.result.WaitForResult != nil {
+		str.WriteString(printWaitForResult(*step.result.WaitForResult))
+	}
+
+	if step.result.HTTPLoadResult != nil {
+		str.WriteString(printHTTPLoadResult(*step.result.HTTPLoadResult))
+	}
+
+	if step.result.ParallelResult != nil {
+		str.WriteString(printParallelResult(*step.result.ParallelResult))
+	}
+	return str.String()
+}
+
+// renderIterationsResult shows the details of the first failed iteration of a
+// repeat or foreach step, or of the last one when they all passed.
+func renderIterationsResult(step stepModel) string {
+	if step.result.IterationsErr != "" {
+		return fmt.Sprintf("  Err: %v\n\n", step.result.IterationsErr)
+	}
+
+	shown := len(step.result.Iterations) - 1
+	for i, test := range step.tests {
+		if i < len(step.result.Iterations) && test.passed != nil && !*test.passed {
+			shown = i
+			break
+		}
+	}
+	iteration := step.result.Iterations[shown]
+	return fmt.Sprintf(" > Iteration %d/%d:\n", shown+1, len(step.result.Iterations)) +
+		renderStepResult(stepModel{result: &iteration})
+}
+
+// printHTTPLoadResult shows the status codes, latency percentiles and a
+// histogram of the latencies with bars scaled to the fullest bucket.
+func printHTTPLoadResult(result api.HTTPLoadResult) string {
+	if result.Err != "" {
+		return fmt.Sprintf("  Err: %v\n\n", result.Err)
+	}
+
+	var str strings.Builder
+	statusCodes := slices.Sorted(maps.Keys(result.StatusCodes))
+	statuses := make([]string, 0, len(statusCodes)+1)
+	for _, code := range statusCodes {
+		statuses = append(statuses, fmt.Sprintf("%dx %d", result.StatusCodes[code], code))
+	}
+	if result.Errors > 0 {
+		statuses = append(statuses, fmt.Sprintf("%dx failed", result.Errors))
+	}
+	fmt.Fprintf(&str, " > %d requests in %dms: %s\n", result.Requests, result.DurationMs, strings.Join(statuses, ", "))
+	fmt.Fprintf(&str, " > Latency p50: %.1fms, p95: %.1fms, p99: %.1fms, max: %.1fms\n", result.P50Ms, result.P95Ms, result.P99Ms, result.MaxMs)
+	str.WriteString(renderLatencyHistogram(result.Histogram))
+	str.WriteByte('\n')
+	return str.String()
+}
+
+func renderLatencyHistogram(buckets []api.HTTPLoadBucket) string {
+	const barWidth = 30
+	largest := 0
+	for _, bucket := range buckets {
+		largest = max(largest, bucket.Count)
+	}
+	if largest == 0 {
+		return ""
+	}
+
+	var str strings.Builder
+	for _, bucket := range buckets {
+		bar := strings.Repeat("█", bucket.Count*barWidth/largest)
+		if bar == "" && bucket.Count > 0 {
+			bar = "▏"
+		}
+		fmt.Fprintf(&str, "   ≤ %8.1fms %s %d\n", bucket.UpToMs, gray.Render(fmt.Sprintf("%-*s", barWidth, bar)), bucket.Count)
+	}
+	return str.String()
+}
+
+// printParallelResult shows one line per concurrent step, numbered like the
+// step's tests, followed by how often each outcome came up.
+func printParallelResult(result api.ParallelResult) string {
+	var str strings.Builder
+	counts := map[string]int{}
+	var outcomes []string
+	str.WriteString(" > Parallel results:\n")
+	for i, stepResult := range result.Results {
+		outcome := parallelOutcome(stepResult)
+		if counts[outcome] == 0 {
+			outcomes = append(outcomes, outcome)
+		}
+		counts[outcome]++
+		fmt.Fprintf(&str, "   [%d/%d] %s\n", i+1, len(result.Results), outcome)
+	}
+
+	summary := make([]string, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		summary = append(summary, fmt.Sprintf("%dx %s", counts[outcome], outcome))
+	}
+	fmt.Fprintf(&str, " > Summary: %s\n\n", strings.Join(summary, ", "))
 	return str.String()
 }
 
+func parallelOutcome(result api.CLIStepResult) string {
+	switch {
+	case result.HTTPRequestResult != nil && result.HTTPRequestResult.Err != "":
+		return "Err: " + result.HTTPRequestResult.Err
+	case result.HTTPRequestResult != nil:
+		return fmt.Sprintf("status %d", result.HTTPRequestResult.StatusCode)
+	case result.CLICommandResult != nil && result.CLICommandResult.Outcome == api.CLICommandSignaled:
+		return "killed by signal " + result.CLICommandResult.Signal
+	case result.CLICommandResult != nil && result.CLICommandResult.Err != "":
+		return "Err: " + result.CLICommandResult.Err
+	case result.CLICommandResult != nil:
+		return fmt.Sprintf("exit code %d", result.CLICommandResult.ExitCode)
+	default:
+		return "no result"
+	}
+}
+
+func printBackgroundProcessResult(result api.BackgroundProcessResult) string {
+	if result.Err != "" {
+		return fmt.Sprintf("  Err: %v\n\n", result.Err)
+	}
+
+	var str strings.Builder
+	if result.ExitedEarly {
+		fmt.Fprintf(&str, "\n > Background process exited early with code %d\n", result.ExitCode)
+	}
+	if result.Stdout != "" {
+		str.WriteString(" > Background process stdout:\n\n")
+		str.WriteString(gray.Render(truncateVisualOutput(result.Stdout)))
+		str.WriteByte('\n')
+	}
+	if result.Stderr != "" {
+		str.WriteString(" > Background process stderr:\n\n")
+		str.WriteString(gray.Render(truncateVisualOutput(result.Stderr)))
+		str.WriteByte('\n')
+	}
+	return str.String()
+}
+
+func printSessionResult(result api.SessionResult) string {
+	var str strings.Builder
+	if result.Err != "" {
+		fmt.Fprintf(&str, "  Err: %v\n\n", result.Err)
+	}
+	str.WriteString(" > Session transcript:\n\n")
+	str.WriteString(gray.Render(truncateVisualOutput(result.Stdout)))
+	str.WriteByte('\n')
+	return str.String()
+}
+
+func printFilesystemResult(result api.FilesystemResult) string {
+	var str strings.Builder
+	for _, entry := range result.Entries {
+		switch {
+		case entry.Err != "":
+			fmt.Fprintf(&str, " > %s: %s\n", entry.Path, entry.Err)
+		case !entry.Exists:
+			fmt.Fprintf(&str, " > %s: [not found]\n", entry.Path)
+		case entry.IsDir:
+			fmt.Fprintf(&str, " > %s: directory (mode %s)\n", entry.Path, entry.Mode)
+		default:
+			fmt.Fprintf(&str, " > %s: file (mode %s)\n", entry.Path, entry.Mode)
+		}
+		if entry.SHA256 != "" {
+			fmt.Fprintf(&str, "   sha256: %s\n", entry.SHA256)
+		}
+		if entry.DirEntries != nil {
+			str.WriteString(gray.Render(truncateVisualOutput(strings.Join(entry.DirEntries, "\n"))))
+			str.WriteString("\n")
+		}
+		if entry.Contents != "" {
+			str.WriteByte('\n')
+			str.WriteString(gray.Render(truncateVisualOutput(entry.Contents)))
+			str.WriteString("\n\n")
+		}
+	}
+	return str.String()
+}
+
+func printWaitForResult(result api.WaitForResult) string {
+	if result.Err != "" {
+		return fmt.Sprintf("  Err: %v\n\n", result.Err)
+	}
+	return fmt.Sprintf("  Ready after %dms (%d attempt(s))\n\n", result.ElapsedMs, result.Attempts)
+}
+
+// renderFailingStdoutLine shows the lines around the one a failed stdoutLines
+// test is about, with the offending line marked.
+func renderFailingStdoutLine(step stepModel, result api.CLICommandResult) string {
+	const contextLines = 2
+	for i, test := range step.tests {
+		if test.passed == nil || *test.passed || i >= len(result.Command.Tests) {
+			continue
+		}
+		linesTest := result.Command.Tests[i].StdoutLines
+		if linesTest == nil {
+			continue
+		}
+		lineNumber := checks.StdoutLinesFailureLine(result, *linesTest)
+		if lineNumber == 0 {
+			continue
+		}
+
+		lines := strings.Split(result.Stdout, "\n")
+		width := len(strconv.Itoa(min(lineNumber+contextLines, len(lines))))
+		var str strings.Builder
+		fmt.Fprintf(&str, " > Stdout line %d:\n\n", lineNumber)
+		for n := max(lineNumber-contextLines, 1); n <= min(lineNumber+contextLines, len(lines)); n++ {
+			line := fmt.Sprintf("%*d | %s", width, n, lines[n-1])
+			if n == lineNumber {
+				str.WriteString(red.Render("> "+line) + "\n")
+			} else {
+				str.WriteString(gray.Render("  "+line) + "\n")
+			}
+		}
+		return str.String()
+	}
+	return ""
+}
+
 func truncateVisualOutput(output string) string {
 	const maxLines, maxRunes = 32, 5120
 	var str strings.Builder
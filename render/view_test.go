@@ -184,6 +184,87 @@ func TestStartStepFallsBackToTechnicalDescription(t *testing.T) {
 	}
 }
 
+func TestStartStepLabelsBackgroundCommands(t *testing.T) {
+	m := initModel(true, false)
+	updated, _ := m.Update(messages.StartStepMsg{CMD: "go run .", Background: true})
+	got := updated.(rootModel).steps[0]
+
+	if got.description != "go run ." {
+		t.Fatalf("description = %q, want command fallback", got.description)
+	}
+	if got.detail != "Background command: go run ." {
+		t.Fatalf("detail = %q, want background command", got.detail)
+	}
+}
+
+func TestWaitProgressShowsOnUnfinishedStep(t *testing.T) {
+	m := initModel(true, false)
+	updated, _ := m.Update(messages.StartStepMsg{WaitFor: "tcp localhost:8080"})
+	updated, _ = updated.Update(messages.WaitProgressMsg{Attempt: 3, ElapsedMs: 1500, TimeoutMs: 30000})
+	got := updated.(rootModel)
+
+	if got.steps[0].detail != "Wait for: tcp localhost:8080" {
+		t.Fatalf("detail = %q, want wait target", got.steps[0].detail)
+	}
+	if view := got.View(); !strings.Contains(view, "attempt 3, 1.5s/30.0s") {
+		t.Fatalf("view missing wait progress\n%s", view)
+	}
+
+	got.steps[0].finished = true
+	if view := got.View(); strings.Contains(view, "attempt 3") {
+		t.Fatalf("view unexpectedly shows progress after finishing\n%s", view)
+	}
+}
+
+func TestRetriedStepShowsEachFailedAttempt(t *testing.T) {
+	m := initModel(true, true)
+	updated, _ := m.Update(messages.StartStepMsg{Method: "GET", URL: "http://localhost:8080/jobs/1"})
+	updated, _ = updated.Update(messages.RetryStepMsg{Index: 0, Attempt: 1, MaxAttempts: 3, Error: "expected status code 200, got 503"})
+	got := updated.(rootModel)
+
+	if view := got.View(); !strings.Contains(view, "attempt 2/3") {
+		t.Fatalf("view missing retry progress\n%s", view)
+	}
+
+	got.finalized = true
+	got.steps[0].finished = true
+	if view := got.View(); !strings.Contains(view, "Attempt 1/3 failed: expected status code 200, got 503") {
+		t.Fatalf("view missing failed attempt\n%s", view)
+	}
+}
+
+func TestFailedStdoutLinesTestPointsAtLine(t *testing.T) {
+	passed := true
+	failed := false
+	line := 3
+	want := "gamma"
+	step := stepModel{
+		finished: true,
+		passed:   &failed,
+		tests: []testModel{
+			{text: "Expect exit code 0", passed: &passed, finished: true},
+			{text: "Expect stdout line 3 to equal 'gamma'", passed: &failed, finished: true},
+		},
+		result: &api.CLIStepResult{CLICommandResult: &api.CLICommandResult{
+			Stdout: "alpha\nbeta\nGAMMA\ndelta\nepsilon\nzeta",
+			Command: api.CLIStepCLICommand{Tests: []api.CLICommandTest{
+				{ExitCode: new(int)},
+				{StdoutLines: &api.StdoutLinesTest{Line: &line, Equals: &want}},
+			}},
+		}},
+	}
+
+	got := renderStepResult(step)
+	for _, expected := range []string{" > Stdout line 3:", "  2 | beta", "> 3 | GAMMA", "  5 | epsilon"} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("result missing %q\n%s", expected, got)
+		}
+	}
+	if strings.Contains(got, "6 | zeta") {
+		t.Errorf("result shows lines outside the context window\n%s", got)
+	}
+}
+
 func TestCompactStepHonorsSubmitMode(t *testing.T) {
 	step := stepModel{description: "A completed step", finished: true}
 
@@ -234,3 +315,128 @@ func TestHTTPRequestBodyUsesVisualOutputLimit(t *testing.T) {
 		t.Fatalf("expected HTTP response body to be visually truncated")
 	}
 }
+
+func TestViewRedactsSecretVariables(t *testing.T) {
+	passed := true
+	m := initModel(false, true)
+	updated, _ := m.Update(messages.SecretVariablesMsg{Names: []string{"apiKey"}})
+	updated, _ = updated.Update(messages.StartStepMsg{CMD: "curl -H 'Key: ${apiKey}' localhost"})
+	updated, _ = updated.Update(messages.ResolveStepMsg{
+		Index:  0,
+		Passed: &passed,
+		Result: &api.CLIStepResult{CLICommandResult: &api.CLICommandResult{
+			Stdout:    "authorized with sk_live_123",
+			Variables: map[string]string{"apiKey": "sk_live_123"},
+			Command:   api.CLIStepCLICommand{Command: "curl -H 'Key: ${apiKey}' localhost"},
+		}},
+	})
+	got := updated.(rootModel)
+	got.finalized = true
+
+	view := got.View()
+	if strings.Contains(view, "sk_live_123") {
+		t.Fatalf("view leaks secret\n%s", view)
+	}
+	for _, want := range []string{"authorized with [secret]", "apiKey: [secret] (Command)"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q\n%s", want, view)
+		}
+	}
+}
+
+func TestSkippedStepRendersReasonAndIgnoresSubmissionResults(t *testing.T) {
+	passed := true
+	m := initModel(true, false)
+	updated, _ := m.Update(messages.StartStepMsg{Description: "Clean up", CMD: "rm -rf tmp"})
+	updated, _ = updated.Update(messages.SkipStepMsg{Index: 0, Reason: "only runs on linux"})
+	updated, _ = updated.Update(messages.ResolveStepMsg{Index: 0, Passed: &passed})
+	updated, _ = updated.Update(messages.ResolveTestMsg{StepIndex: 0, TestIndex: 0, Passed: &passed})
+	got := updated.(rootModel)
+	got.finalized = true
+
+	view := got.View()
+	if !strings.Contains(view, "Clean up (skipped: only runs on linux)") {
+		t.Fatalf("view missing skipped step\n%s", view)
+	}
+	if strings.Contains(view, "✓") || strings.Contains(view, "rm -rf tmp") {
+		t.Fatalf("skipped step rendered as run\n%s", view)
+	}
+}
+
+func TestParallelResultListsEachCopyAndSummary(t *testing.T) {
+	got := printParallelResult(api.ParallelResult{Results: []api.CLIStepResult{
+		{HTTPRequestResult: &api.HTTPRequestResult{StatusCode: 200}},
+		{HTTPRequestResult: &api.HTTPRequestResult{StatusCode: 429}},
+		{HTTPRequestResult: &api.HTTPRequestResult{StatusCode: 429}},
+		{CLICommandResult: &api.CLICommandResult{ExitCode: 0}},
+	}})
+
+	for _, want := range []string{
+		"[1/4] status 200",
+		"[3/4] status 429",
+		"[4/4] exit code 0",
+		"Summary: 1x status 200, 2x status 429, 1x exit code 0",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("parallel result missing %q\n%s", want, got)
+		}
+	}
+}
+
+func TestHTTPLoadResultShowsPercentilesAndHistogram(t *testing.T) {
+	got := printHTTPLoadResult(api.HTTPLoadResult{
+		Requests:    10,
+		Errors:      1,
+		StatusCodes: map[int]int{200: 8, 429: 1},
+		P50Ms:       4,
+		P95Ms:       9.5,
+		P99Ms:       10,
+		MaxMs:       10,
+		DurationMs:  42,
+		Histogram:   []api.HTTPLoadBucket{{UpToMs: 5, Count: 6}, {UpToMs: 10, Count: 3}},
+	})
+
+	for _, want := range []string{
+		"10 requests in 42ms: 8x 200, 1x 429, 1x failed",
+		"p50: 4.0ms, p95: 9.5ms, p99: 10.0ms",
+		"≤      5.0ms " + strings.Repeat("█", 30),
+		"≤     10.0ms " + strings.Repeat("█", 15),
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("load result missing %q\n%s", want, got)
+		}
+	}
+}
+
+func TestLoopStepShowsFailedIteration(t *testing.T) {
+	passed, failed := true, false
+	m := initModel(false, false)
+	updated, _ := m.Update(messages.StartStepMsg{CMD: "echo ${index}", Loop: "3 times"})
+	got := updated.(rootModel)
+	if detail := got.steps[0].detail; detail != "Command: echo ${index} (3 times)" {
+		t.Fatalf("detail = %q, want the loop described", detail)
+	}
+
+	got.steps[0].tests = []testModel{
+		{text: "Iteration 1/2: echo 0", passed: &passed, finished: true},
+		{text: "Iteration 2/2: echo 1", passed: &failed, finished: true},
+	}
+	got.steps[0].result = &api.CLIStepResult{Iterations: []api.CLIStepResult{
+		{CLICommandResult: &api.CLICommandResult{Stdout: "zero"}},
+		{CLICommandResult: &api.CLICommandResult{Stdout: "one"}},
+	}}
+	view := renderStepResult(got.steps[0])
+	if !strings.Contains(view, "Iteration 2/2:") || !strings.Contains(view, "one") || strings.Contains(view, "zero") {
+		t.Fatalf("iteration result doesn't show the failed iteration\n%s", view)
+	}
+}
+
+func TestHTTPResultShowsRequestBodySummary(t *testing.T) {
+	got := printHTTPRequestResult(api.HTTPRequestResult{
+		StatusCode:  201,
+		RequestBody: "[binary image/png, 8 B]",
+	})
+	if !strings.Contains(got, "Request Body: \n[binary image/png, 8 B]") {
+		t.Fatalf("result missing request body summary\n%s", got)
+	}
+}